@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
+	_ "time/tzdata"
 
 	"dirav-backend/internal/api/handlers"
 	"dirav-backend/internal/api/routes"
 	"dirav-backend/internal/config"
 	"dirav-backend/internal/database"
+	"dirav-backend/internal/notify"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -24,10 +28,12 @@ func main() {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
+	config.ExposeHeaders = []string{"X-Next-Cursor"}
 	r.Use(cors.New(config))
-	h := &handlers.Handler{DB: db, JWTSecret: cfg.JWTSecret}
+	h := &handlers.Handler{DB: db, JWTSecret: cfg.JWTSecret, Notifier: notify.Log{}}
 
 	routes.Register(r, h)
+	go h.RunSavingsWorker(context.Background(), time.Minute)
 
 	if err := r.Run(":" + cfg.Port); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,104 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"dirav-backend/internal/categories"
+)
+
+// CategoryTotal is the money in one category over a period and the period
+// before it.
+type CategoryTotal struct {
+	Category      string  `json:"category"`
+	Total         float64 `json:"total"`
+	Count         int     `json:"count"`
+	PreviousTotal float64 `json:"previous_total"`
+	PreviousCount int     `json:"previous_count"`
+	// Percent is Total as a share of the period's total over all
+	// categories.
+	Percent      float64  `json:"percent"`
+	DeltaPercent *float64 `json:"delta_percent"`
+}
+
+// ParentTotal is a top-level category with the categories under it, its
+// own transactions included under its own name.
+type ParentTotal struct {
+	CategoryTotal
+	Children []CategoryTotal `json:"children"`
+}
+
+// Breakdown groups category totals under their top-level categories,
+// fills in shares and changes, and sorts parents and children largest
+// first. Top-level categories differing only in case are one parent,
+// named as first seen. It returns the parents and the grand total.
+func Breakdown(rows []CategoryTotal) ([]ParentTotal, CategoryTotal) {
+	var grand CategoryTotal
+	index := map[string]int{}
+	var parents []ParentTotal
+	for _, r := range rows {
+		root := categories.Root(r.Category)
+		key := strings.ToLower(root)
+		i, ok := index[key]
+		if !ok {
+			i = len(parents)
+			index[key] = i
+			parents = append(parents, ParentTotal{CategoryTotal: CategoryTotal{Category: root}})
+		}
+		p := &parents[i]
+		p.add(r)
+		p.Children = append(p.Children, r)
+		grand.add(r)
+	}
+
+	for i := range parents {
+		p := &parents[i]
+		p.finish(grand.Total)
+		for j := range p.Children {
+			p.Children[j].finish(grand.Total)
+		}
+		sortTotals(p.Children, func(j int) CategoryTotal { return p.Children[j] })
+	}
+	sortTotals(parents, func(i int) CategoryTotal { return parents[i].CategoryTotal })
+	grand.finish(grand.Total)
+	if parents == nil {
+		parents = []ParentTotal{}
+	}
+	return parents, grand
+}
+
+func (t *CategoryTotal) add(r CategoryTotal) {
+	t.Total += r.Total
+	t.Count += r.Count
+	t.PreviousTotal += r.PreviousTotal
+	t.PreviousCount += r.PreviousCount
+}
+
+func (t *CategoryTotal) finish(grand float64) {
+	t.Total, t.PreviousTotal = round(t.Total), round(t.PreviousTotal)
+	t.Percent = 0
+	if grand != 0 {
+		t.Percent = round(t.Total / grand * 100)
+	}
+	t.DeltaPercent = DeltaPercent(t.Total, t.PreviousTotal)
+}
+
+// sortTotals orders by total, then previous total, both descending, then
+// by name.
+func sortTotals[T any](list []T, at func(int) CategoryTotal) {
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := at(i), at(j)
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		if a.PreviousTotal != b.PreviousTotal {
+			return a.PreviousTotal > b.PreviousTotal
+		}
+		return a.Category < b.Category
+	})
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
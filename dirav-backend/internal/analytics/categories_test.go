@@ -0,0 +1,57 @@
+package analytics
+
+import "testing"
+
+func TestBreakdown(t *testing.T) {
+	rows := []CategoryTotal{
+		{Category: "Rent", Total: 500, Count: 1, PreviousTotal: 500, PreviousCount: 1},
+		{Category: "Food:Groceries", Total: 300, Count: 6, PreviousTotal: 200, PreviousCount: 4},
+		{Category: "Food", Total: 50, Count: 1},
+		{Category: "food:Eating out", Total: 150, Count: 3, PreviousTotal: 300, PreviousCount: 5},
+		{Category: "Travel", PreviousTotal: 400, PreviousCount: 1},
+	}
+	parents, grand := Breakdown(rows)
+
+	if grand.Total != 1000 || grand.Count != 11 || grand.PreviousTotal != 1400 || *grand.DeltaPercent != -28.57 {
+		t.Errorf("grand total: %+v", grand)
+	}
+	if len(parents) != 3 {
+		t.Fatalf("got %d parents, want 3", len(parents))
+	}
+
+	// Food has the most, counted across case; Travel, with nothing this
+	// period, comes last.
+	food := parents[0]
+	if food.Category != "Food" || food.Total != 500 || food.Count != 10 || food.PreviousTotal != 500 {
+		t.Errorf("food: %+v", food.CategoryTotal)
+	}
+	if food.Percent != 50 || food.DeltaPercent == nil || *food.DeltaPercent != 0 {
+		t.Errorf("food share %v, delta %v; want 50, 0", food.Percent, food.DeltaPercent)
+	}
+	if parents[1].Category != "Rent" || parents[2].Category != "Travel" {
+		t.Errorf("order: %s, %s; want Rent, Travel", parents[1].Category, parents[2].Category)
+	}
+	if d := parents[2].DeltaPercent; d == nil || *d != -100 {
+		t.Errorf("travel delta %v, want -100", d)
+	}
+
+	want := []string{"Food:Groceries", "food:Eating out", "Food"}
+	for i, c := range food.Children {
+		if c.Category != want[i] {
+			t.Errorf("child %d is %s, want %s", i, c.Category, want[i])
+		}
+	}
+	if c := food.Children[0]; c.Percent != 30 || *c.DeltaPercent != 50 {
+		t.Errorf("groceries share %v, delta %v; want 30, 50", c.Percent, *c.DeltaPercent)
+	}
+	if c := food.Children[2]; c.DeltaPercent != nil {
+		t.Errorf("food with nothing before: delta %v, want nil", *c.DeltaPercent)
+	}
+}
+
+func TestBreakdownEmpty(t *testing.T) {
+	parents, grand := Breakdown(nil)
+	if parents == nil || len(parents) != 0 || grand.Total != 0 || grand.Percent != 0 {
+		t.Errorf("got %v, %+v", parents, grand)
+	}
+}
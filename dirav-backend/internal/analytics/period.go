@@ -0,0 +1,103 @@
+// Package analytics compares money over periods of calendar days. Days are
+// dates at midnight UTC, like transaction dates, taken in the user's
+// timezone.
+package analytics
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrInvalidPeriod   = errors.New("from must not be after to")
+	ErrInvalidTimezone = errors.New("invalid timezone")
+)
+
+// Period is a range of calendar days, both ends included.
+type Period struct {
+	From time.Time
+	To   time.Time
+}
+
+// NewPeriod returns the period from from to to, which must not be before
+// from.
+func NewPeriod(from, to time.Time) (Period, error) {
+	p := Period{From: dateOf(from), To: dateOf(to)}
+	if p.From.After(p.To) {
+		return Period{}, ErrInvalidPeriod
+	}
+	return p, nil
+}
+
+// MonthToDate is the period from the first of today's month to today.
+func MonthToDate(today time.Time) Period {
+	today = dateOf(today)
+	return Period{From: today.AddDate(0, 0, 1-today.Day()), To: today}
+}
+
+// Month is the whole calendar month containing day.
+func Month(day time.Time) Period {
+	first := dateOf(day).AddDate(0, 0, 1-day.Day())
+	return Period{From: first, To: first.AddDate(0, 1, -1)}
+}
+
+// End is the day after the period, for half-open date comparisons.
+func (p Period) End() time.Time {
+	return p.To.AddDate(0, 0, 1)
+}
+
+// Days is how many days the period covers.
+func (p Period) Days() int {
+	return int(math.Round(p.End().Sub(p.From).Hours() / 24))
+}
+
+// Previous is the period p is compared against. A period starting on the
+// first of a month and ending in that month is compared with the same
+// days of the month before, clamped to its end: 1-15 March with 1-15
+// February, and all of March with all of February. Any other period is
+// compared with as many days just before it.
+func (p Period) Previous() Period {
+	if p.From.Day() == 1 && p.To.Year() == p.From.Year() && p.To.Month() == p.From.Month() {
+		prev := Month(p.From.AddDate(0, 0, -1))
+		to := prev.From.AddDate(0, 0, p.To.Day()-1)
+		if to.After(prev.To) {
+			to = prev.To
+		}
+		return Period{From: prev.From, To: to}
+	}
+	return Period{From: p.From.AddDate(0, 0, -p.Days()), To: p.From.AddDate(0, 0, -1)}
+}
+
+// DeltaPercent is the change from prev to cur in percent, rounded to two
+// decimals. It is nil when prev is zero, as there is no change to express.
+func DeltaPercent(cur, prev float64) *float64 {
+	if prev == 0 {
+		return nil
+	}
+	d := math.Round((cur-prev)/math.Abs(prev)*10000) / 100
+	return &d
+}
+
+// Today is the current calendar day in loc, as a date.
+func Today(now time.Time, loc *time.Location) time.Time {
+	return dateOf(now.In(loc))
+}
+
+// LoadLocation loads a timezone preference: an IANA name such as
+// "Europe/Berlin". The server's local zone is refused, since it differs
+// between deployments.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
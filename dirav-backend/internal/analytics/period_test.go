@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestPrevious(t *testing.T) {
+	tests := []struct {
+		name             string
+		from, to         string
+		prevFrom, prevTo string
+	}{
+		{"month to date", "2026-03-01", "2026-03-15", "2026-02-01", "2026-02-15"},
+		{"clamped to a shorter month", "2026-03-01", "2026-03-30", "2026-02-01", "2026-02-28"},
+		{"whole month", "2026-03-01", "2026-03-31", "2026-02-01", "2026-02-28"},
+		{"across a year", "2026-01-01", "2026-01-10", "2025-12-01", "2025-12-10"},
+		{"arbitrary days", "2026-03-10", "2026-03-16", "2026-03-03", "2026-03-09"},
+		{"across months", "2026-03-01", "2026-04-15", "2026-01-14", "2026-02-28"},
+	}
+	for _, tt := range tests {
+		p, err := NewPeriod(day(tt.from), day(tt.to))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		prev := p.Previous()
+		if !prev.From.Equal(day(tt.prevFrom)) || !prev.To.Equal(day(tt.prevTo)) {
+			t.Errorf("%s: previous is %s to %s, want %s to %s", tt.name,
+				prev.From.Format("2006-01-02"), prev.To.Format("2006-01-02"), tt.prevFrom, tt.prevTo)
+		}
+	}
+}
+
+func TestNewPeriod(t *testing.T) {
+	if _, err := NewPeriod(day("2026-03-02"), day("2026-03-01")); err != ErrInvalidPeriod {
+		t.Errorf("reversed period: %v, want ErrInvalidPeriod", err)
+	}
+	p, err := NewPeriod(day("2026-03-01"), day("2026-03-01"))
+	if err != nil || p.Days() != 1 || !p.End().Equal(day("2026-03-02")) {
+		t.Errorf("single day: %+v, %v", p, err)
+	}
+}
+
+func TestMonthToDate(t *testing.T) {
+	p := MonthToDate(time.Date(2026, 3, 15, 18, 30, 0, 0, time.UTC))
+	if !p.From.Equal(day("2026-03-01")) || !p.To.Equal(day("2026-03-15")) {
+		t.Errorf("got %s to %s", p.From, p.To)
+	}
+}
+
+func TestDeltaPercent(t *testing.T) {
+	if d := DeltaPercent(150, 120); d == nil || *d != 25 {
+		t.Errorf("150 against 120: %v, want 25", d)
+	}
+	if d := DeltaPercent(80, 120); d == nil || *d != -33.33 {
+		t.Errorf("80 against 120: %v, want -33.33", d)
+	}
+	if d := DeltaPercent(80, 0); d != nil {
+		t.Errorf("nothing before: %v, want nil", *d)
+	}
+}
+
+func TestToday(t *testing.T) {
+	// 23:30 UTC on 31 March is already 1 April in Berlin and still
+	// 31 March in New York.
+	now := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)
+	berlin, err := LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Today(now, berlin); !got.Equal(day("2026-04-01")) {
+		t.Errorf("Berlin: %s, want 2026-04-01", got)
+	}
+	if got := Today(now, newYork); !got.Equal(day("2026-03-31")) {
+		t.Errorf("New York: %s, want 2026-03-31", got)
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	for _, name := range []string{"", "Local", "Mars/Olympus"} {
+		if _, err := LoadLocation(name); err != ErrInvalidTimezone {
+			t.Errorf("%q: %v, want ErrInvalidTimezone", name, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 
 	"dirav-backend/internal/models"
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,15 @@ type accountRequest struct {
 	IsPrimary   bool    `json:"is_primary"`
 }
 
+var accountList = listSpec{
+	sorts: map[string]sortField{
+		"name":       {column: "account_name", field: "AccountName", kind: "text"},
+		"balance":    {column: "balance", field: "Balance", kind: "number"},
+		"created_at": {column: "created_at", field: "CreatedAt", kind: "time"},
+	},
+	defaultSort: "created_at",
+}
+
 func (h *Handler) ListAccounts(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
@@ -23,12 +33,18 @@ func (h *Handler) ListAccounts(c *gin.Context) {
 		return
 	}
 
-	var accounts []models.Account
-	if err := h.DB.Where("user_id = ?", userID).Find(&accounts).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
-		return
+	query := h.DB.Where("user_id = ?", userID)
+	if t := c.Query("account_type"); t != "" {
+		query = query.Where("account_type = ?", t)
 	}
-	c.JSON(http.StatusOK, accounts)
+	if cur := c.Query("currency"); cur != "" {
+		query = query.Where("currency = ?", cur)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("account_name ILIKE ?", likePattern(q))
+	}
+
+	listPage[models.Account](c, query, accountList)
 }
 
 func (h *Handler) CreateAccount(c *gin.Context) {
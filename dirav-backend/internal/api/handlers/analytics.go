@@ -4,10 +4,14 @@ import (
 	"net/http"
 	"time"
 
+	"dirav-backend/internal/analytics"
 	"dirav-backend/internal/models"
 	"github.com/gin-gonic/gin"
 )
 
+// Summary gives balances, savings and spending. Spending over a period,
+// the month so far by default, is compared with the period before it;
+// periods are counted in the user's timezone.
 func (h *Handler) Summary(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
@@ -24,17 +28,35 @@ func (h *Handler) Summary(c *gin.Context) {
 		return
 	}
 
+	// Savings are what the user's own goals hold plus their part of goals
+	// shared with them: what they put in less what they took out, never
+	// below zero, as the owner may withdraw what others contributed.
 	var savings float64
-	if err := h.DB.Model(&models.SavingsGoal{}).
-		Where("user_id = ?", userID).
-		Select("COALESCE(SUM(current_amount), 0)").
+	if err := h.DB.Raw(`SELECT
+	COALESCE((SELECT SUM(current_amount) FROM savings_goals WHERE user_id = ?), 0) +
+	COALESCE((SELECT SUM(GREATEST(net, 0)) FROM (
+		SELECT SUM(c.amount) AS net
+		FROM savings_contributions c
+		JOIN savings_goals g ON g.id = c.savings_goal_id
+		WHERE c.user_id = ? AND g.user_id <> ?
+		GROUP BY c.savings_goal_id
+	) shared), 0)`, userID, userID, userID).
 		Scan(&savings).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
 
+	// The allowance the user set wins; without one, the largest monthly
+	// budget stands in for it.
+	var user models.User
+	if err := h.DB.Select("monthly_allowance").First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
 	var allowance float64
-	if err := h.DB.Model(&models.Budget{}).
+	if user.MonthlyAllowance != nil {
+		allowance = *user.MonthlyAllowance
+	} else if err := h.DB.Model(&models.Budget{}).
 		Where("user_id = ? AND period = ? AND is_active = true", userID, "monthly").
 		Select("COALESCE(MAX(amount), 0)").
 		Scan(&allowance).Error; err != nil {
@@ -42,19 +64,40 @@ func (h *Handler) Summary(c *gin.Context) {
 		return
 	}
 
-	start := time.Date(time.Now().Year(), time.Now().Month(), 1, 0, 0, 0, 0, time.UTC)
-	end := start.AddDate(0, 1, 0)
+	loc, err := userLocation(h.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	today := analytics.Today(h.forecaster().Clock.Now(), loc)
+	period, err := queryPeriod(c, today)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	previous := period.Previous()
+	month := analytics.Month(today)
 
-	var spent float64
+	// Money moved between accounts or into savings is not spending.
+	const between = "transaction_date >= ? AND transaction_date < ?"
+	var spent struct {
+		Period   float64
+		Previous float64
+		Month    float64
+	}
 	if err := h.DB.Model(&models.Transaction{}).
-		Where("user_id = ? AND type = ? AND transaction_date >= ? AND transaction_date < ?", userID, "expense", start, end).
-		Select("COALESCE(SUM(amount), 0)").
+		Select("COALESCE(SUM(amount) FILTER (WHERE "+between+"), 0) AS period, "+
+			"COALESCE(SUM(amount) FILTER (WHERE "+between+"), 0) AS previous, "+
+			"COALESCE(SUM(amount) FILTER (WHERE "+between+"), 0) AS month",
+			period.From, period.End(), previous.From, previous.End(), month.From, month.End()).
+		Where("user_id = ? AND type = ? AND is_transfer = false", userID, "expense").
+		Where("transaction_date >= ?", earliest(period.From, previous.From, month.From)).
 		Scan(&spent).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
 
-	remaining := allowance - spent
+	remaining := allowance - spent.Month
 	if remaining < 0 {
 		remaining = 0
 	}
@@ -63,8 +106,112 @@ func (h *Handler) Summary(c *gin.Context) {
 		"balance":              balance,
 		"savings":              savings,
 		"monthly_allowance":    allowance,
-		"spent_this_month":     spent,
-		"remaining_this_month": remaining,
-		"delta_percent":        0,
+		"spent_this_month":     roundCents(spent.Month),
+		"remaining_this_month": roundCents(remaining),
+		"timezone":             loc.String(),
+		"from":                 period.From.Format("2006-01-02"),
+		"to":                   period.To.Format("2006-01-02"),
+		"spent":                roundCents(spent.Period),
+		"previous_from":        previous.From.Format("2006-01-02"),
+		"previous_to":          previous.To.Format("2006-01-02"),
+		"previous_spent":       roundCents(spent.Previous),
+		"delta_percent":        analytics.DeltaPercent(spent.Period, spent.Previous),
+	})
+}
+
+// queryPeriod reads the from and to dates, both inclusive. Without them
+// the period is the month so far; from alone runs to today and to alone
+// starts on the first of its month.
+func queryPeriod(c *gin.Context, today time.Time) (analytics.Period, error) {
+	from, err := queryDate(c, "from")
+	if err != nil {
+		return analytics.Period{}, err
+	}
+	to, err := queryDate(c, "to")
+	if err != nil {
+		return analytics.Period{}, err
+	}
+
+	end := today
+	if to != nil {
+		end = *to
+	}
+	start := analytics.MonthToDate(end).From
+	if from != nil {
+		start = *from
+	}
+	return analytics.NewPeriod(start, end)
+}
+
+// CategoryBreakdown totals income or expenses by category over a period,
+// the month so far by default, next to the period before it. Categories are
+// nested under their top-level category.
+func (h *Handler) CategoryBreakdown(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	kind := c.DefaultQuery("type", "expense")
+	if kind != "expense" && kind != "income" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be expense or income"})
+		return
+	}
+
+	loc, err := userLocation(h.DB, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	period, err := queryPeriod(c, analytics.Today(h.forecaster().Clock.Now(), loc))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	previous := period.Previous()
+
+	// Transfers are neither income nor spending.
+	const between = "transaction_date >= ? AND transaction_date < ?"
+	var rows []analytics.CategoryTotal
+	if err := h.DB.Model(&models.Transaction{}).
+		Select("btrim(category) AS category, "+
+			"COALESCE(SUM(amount) FILTER (WHERE "+between+"), 0) AS total, "+
+			"COUNT(*) FILTER (WHERE "+between+") AS count, "+
+			"COALESCE(SUM(amount) FILTER (WHERE "+between+"), 0) AS previous_total, "+
+			"COUNT(*) FILTER (WHERE "+between+") AS previous_count",
+			period.From, period.End(), period.From, period.End(),
+			previous.From, previous.End(), previous.From, previous.End()).
+		Where("user_id = ? AND type = ? AND is_transfer = false", userID, kind).
+		Where("(("+between+") OR ("+between+"))", period.From, period.End(), previous.From, previous.End()).
+		Group("btrim(category)").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	parents, total := analytics.Breakdown(rows)
+	c.JSON(http.StatusOK, gin.H{
+		"type":           kind,
+		"timezone":       loc.String(),
+		"from":           period.From.Format("2006-01-02"),
+		"to":             period.To.Format("2006-01-02"),
+		"previous_from":  previous.From.Format("2006-01-02"),
+		"previous_to":    previous.To.Format("2006-01-02"),
+		"total":          total.Total,
+		"count":          total.Count,
+		"previous_total": total.PreviousTotal,
+		"previous_count": total.PreviousCount,
+		"delta_percent":  total.DeltaPercent,
+		"categories":     parents,
 	})
 }
+
+func earliest(first time.Time, rest ...time.Time) time.Time {
+	for _, t := range rest {
+		if t.Before(first) {
+			first = t
+		}
+	}
+	return first
+}
@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/categories"
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/notify"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListBudgetAlerts returns the user's budget alerts, newest first. They can
+// be narrowed to one budget with budget_id and to unread ones with
+// unread=true.
+func (h *Handler) ListBudgetAlerts(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	budgetID, err := queryUUID(c, "budget_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.DB.Where("user_id = ?", userID)
+	if budgetID != nil {
+		query = query.Where("budget_id = ?", *budgetID)
+	}
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var alerts []models.BudgetAlert
+	if err := query.Order("created_at desc").Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, alerts)
+}
+
+// MarkBudgetAlertRead marks an alert as read.
+func (h *Handler) MarkBudgetAlertRead(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	res := h.DB.Model(&models.BudgetAlert{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		var count int64
+		h.DB.Model(&models.BudgetAlert{}).Where("id = ? AND user_id = ?", id, userID).Count(&count)
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "read"})
+}
+
+// checkBudgetAlerts evaluates the alerts of every active budget the
+// transactions count against. It runs after the transactions are saved;
+// failures are logged rather than returned, as the write itself succeeded.
+func (h *Handler) checkBudgetAlerts(userID uuid.UUID, txs []models.Transaction) {
+	var spending []models.Transaction
+	for _, t := range txs {
+		if t.Type == "expense" && !t.IsTransfer {
+			spending = append(spending, t)
+		}
+	}
+	if len(spending) == 0 {
+		return
+	}
+
+	var budgets []models.Budget
+	if err := h.DB.Where("user_id = ? AND is_active = true", userID).Find(&budgets).Error; err != nil {
+		log.Printf("budget alerts for user %s: %v", userID, err)
+		return
+	}
+	now, err := h.userNow(userID)
+	if err != nil {
+		log.Printf("budget alerts for user %s: %v", userID, err)
+		return
+	}
+	for _, b := range budgets {
+		if len(budgeting.Thresholds(b.AlertThresholds)) == 0 || !budgetCovers(b, spending) {
+			continue
+		}
+		if err := h.evaluateBudgetAlerts(userID, b.ID, now); err != nil {
+			log.Printf("budget alerts for budget %s: %v", b.ID, err)
+		}
+	}
+}
+
+// budgetCovers reports whether any of the transactions counts against the
+// budget.
+func budgetCovers(b models.Budget, txs []models.Transaction) bool {
+	for _, t := range txs {
+		if budgetCounts(b, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// budgetCounts reports whether a transaction's category and account put it
+// against the budget, as budgetWindowSpending selects them.
+func budgetCounts(b models.Budget, t models.Transaction) bool {
+	if !categories.Contains(b.Category, t.Category) {
+		return false
+	}
+	return b.AccountID == nil || (t.AccountID != nil && *t.AccountID == *b.AccountID)
+}
+
+// evaluateBudgetAlerts records an alert for each threshold the budget's
+// current period has reached and delivers the ones not delivered yet.
+func (h *Handler) evaluateBudgetAlerts(userID, budgetID uuid.UUID, now time.Time) error {
+	budget, periods, err := h.budgetPeriods(userID, budgetID, now)
+	if err != nil {
+		return err
+	}
+	period := periods[len(periods)-1]
+	if period.Closed {
+		return nil
+	}
+	return h.raiseBudgetAlerts(budgetAlertDB{h.DB}, budget, period, budgetProgress(budget, period, now))
+}
+
+// raiseBudgetAlerts records an alert for each threshold progress has
+// reached, once per period, then delivers every alert of the period that
+// is not delivered yet. An alert whose delivery failed stays undelivered
+// and is tried again on the next evaluation.
+func (h *Handler) raiseBudgetAlerts(store alertStore, budget models.Budget, period models.BudgetPeriod, progress budgeting.Progress) error {
+	for _, threshold := range budgeting.Reached(budgeting.Thresholds(budget.AlertThresholds), progress.PercentUsed) {
+		alert := models.BudgetAlert{
+			UserID:      budget.UserID,
+			BudgetID:    budget.ID,
+			PeriodStart: period.PeriodStart,
+			Threshold:   threshold,
+			Kind:        budgeting.AlertKind(threshold),
+			PercentUsed: progress.PercentUsed,
+			Spent:       progress.Spent,
+			Amount:      progress.Amount,
+		}
+		if err := store.record(&alert); err != nil {
+			return err
+		}
+	}
+
+	pending, err := store.undelivered(budget.ID, period.PeriodStart)
+	if err != nil {
+		return err
+	}
+	for _, alert := range pending {
+		// Claiming the alert first keeps concurrent evaluations from
+		// delivering it twice.
+		claimed, err := store.claim(alert.ID, time.Now())
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		if err := h.notifier().Notify(context.Background(), budgetAlertMessage(budget, alert, progress)); err != nil {
+			log.Printf("budget alert %s: delivery failed: %v", alert.ID, err)
+			if err := store.release(alert.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// alertStore keeps budget alerts for raiseBudgetAlerts.
+type alertStore interface {
+	// record saves an alert unless its threshold already has one in the
+	// period.
+	record(a *models.BudgetAlert) error
+	// undelivered returns the budget's alerts in the period that are not
+	// delivered, lowest threshold first.
+	undelivered(budgetID uuid.UUID, periodStart time.Time) ([]models.BudgetAlert, error)
+	// claim marks an undelivered alert delivered at at, reporting false
+	// when it already was.
+	claim(id uuid.UUID, at time.Time) (bool, error)
+	// release marks a claimed alert undelivered again.
+	release(id uuid.UUID) error
+}
+
+// budgetAlertDB is the alertStore in the database; the unique index on
+// budget, period and threshold makes record idempotent.
+type budgetAlertDB struct {
+	db *gorm.DB
+}
+
+func (s budgetAlertDB) record(a *models.BudgetAlert) error {
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(a).Error
+}
+
+func (s budgetAlertDB) undelivered(budgetID uuid.UUID, periodStart time.Time) ([]models.BudgetAlert, error) {
+	var alerts []models.BudgetAlert
+	err := s.db.Where("budget_id = ? AND period_start = ? AND delivered_at IS NULL", budgetID, periodStart).
+		Order("threshold").
+		Find(&alerts).Error
+	return alerts, err
+}
+
+func (s budgetAlertDB) claim(id uuid.UUID, at time.Time) (bool, error) {
+	res := s.db.Model(&models.BudgetAlert{}).
+		Where("id = ? AND delivered_at IS NULL", id).
+		Update("delivered_at", at)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (s budgetAlertDB) release(id uuid.UUID) error {
+	return s.db.Model(&models.BudgetAlert{}).Where("id = ?", id).Update("delivered_at", nil).Error
+}
+
+func budgetAlertMessage(b models.Budget, a models.BudgetAlert, p budgeting.Progress) notify.Message {
+	subject := fmt.Sprintf("%s budget reached %d%%", b.Name, a.Threshold)
+	if a.Kind == budgeting.AlertOverBudget {
+		subject = fmt.Sprintf("%s budget is used up", b.Name)
+		if p.Remaining < 0 {
+			subject = fmt.Sprintf("%s budget is over by %.2f", b.Name, -p.Remaining)
+		}
+	}
+	return notify.Message{
+		UserID:  a.UserID,
+		Kind:    "budget_" + a.Kind,
+		Subject: subject,
+		Body: fmt.Sprintf("You have spent %.2f of %.2f (%.0f%%) between %s and %s.",
+			p.Spent, p.Amount, p.PercentUsed, p.PeriodStart, p.PeriodEnd),
+		Ref: a.ID,
+	}
+}
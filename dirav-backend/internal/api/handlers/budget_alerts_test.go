@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/notify"
+	"github.com/google/uuid"
+)
+
+func TestBudgetCovers(t *testing.T) {
+	account, other := uuid.New(), uuid.New()
+	food := models.Budget{Category: "Food"}
+	onAccount := models.Budget{Category: "Food", AccountID: &account}
+
+	cases := []struct {
+		name   string
+		budget models.Budget
+		tx     models.Transaction
+		want   bool
+	}{
+		{"same category", food, models.Transaction{Category: "food"}, true},
+		{"child category", food, models.Transaction{Category: "Food:Groceries"}, true},
+		{"other category", food, models.Transaction{Category: "Rent"}, false},
+		{"any category", models.Budget{}, models.Transaction{Category: "Rent"}, true},
+		{"budget account", onAccount, models.Transaction{Category: "Food", AccountID: &account}, true},
+		{"other account", onAccount, models.Transaction{Category: "Food", AccountID: &other}, false},
+		{"no account", onAccount, models.Transaction{Category: "Food"}, false},
+	}
+	for _, c := range cases {
+		if got := budgetCovers(c.budget, []models.Transaction{c.tx}); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBudgetAlertMessage(t *testing.T) {
+	b := models.Budget{Name: "Food"}
+	alert := models.BudgetAlert{ID: uuid.New(), UserID: uuid.New(), Threshold: 80, Kind: budgeting.AlertThreshold}
+	p := budgeting.Progress{Amount: 200, Spent: 165, Remaining: 35, PercentUsed: 82.5, PeriodStart: "2025-03-01", PeriodEnd: "2025-03-31"}
+
+	rec := &notify.Recorder{}
+	if err := rec.Notify(context.Background(), budgetAlertMessage(b, alert, p)); err != nil {
+		t.Fatal(err)
+	}
+	m := rec.Messages()[0]
+	if m.Subject != "Food budget reached 80%" || m.Kind != "budget_threshold" || m.Ref != alert.ID || m.UserID != alert.UserID {
+		t.Errorf("message = %+v", m)
+	}
+	if !strings.Contains(m.Body, "165.00 of 200.00") {
+		t.Errorf("body = %q", m.Body)
+	}
+
+	alert.Threshold, alert.Kind = 100, budgeting.AlertOverBudget
+	p.Spent, p.Remaining = 230, -30
+	if got := budgetAlertMessage(b, alert, p).Subject; got != "Food budget is over by 30.00" {
+		t.Errorf("over-budget subject = %q", got)
+	}
+}
+
+// memoryAlerts is an alertStore in memory, unique on budget, period and
+// threshold like the database.
+type memoryAlerts struct {
+	alerts []models.BudgetAlert
+}
+
+func (s *memoryAlerts) record(a *models.BudgetAlert) error {
+	for _, x := range s.alerts {
+		if x.BudgetID == a.BudgetID && x.PeriodStart.Equal(a.PeriodStart) && x.Threshold == a.Threshold {
+			return nil
+		}
+	}
+	a.ID = uuid.New()
+	s.alerts = append(s.alerts, *a)
+	return nil
+}
+
+func (s *memoryAlerts) undelivered(budgetID uuid.UUID, periodStart time.Time) ([]models.BudgetAlert, error) {
+	var list []models.BudgetAlert
+	for _, a := range s.alerts {
+		if a.BudgetID == budgetID && a.PeriodStart.Equal(periodStart) && a.DeliveredAt == nil {
+			list = append(list, a)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Threshold < list[j].Threshold })
+	return list, nil
+}
+
+func (s *memoryAlerts) claim(id uuid.UUID, at time.Time) (bool, error) {
+	for i := range s.alerts {
+		if s.alerts[i].ID == id && s.alerts[i].DeliveredAt == nil {
+			s.alerts[i].DeliveredAt = &at
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryAlerts) release(id uuid.UUID) error {
+	for i := range s.alerts {
+		if s.alerts[i].ID == id {
+			s.alerts[i].DeliveredAt = nil
+		}
+	}
+	return nil
+}
+
+func TestRaiseBudgetAlerts(t *testing.T) {
+	rec := &notify.Recorder{}
+	h := &Handler{Notifier: rec}
+	store := &memoryAlerts{}
+	budget := models.Budget{ID: uuid.New(), UserID: uuid.New(), Name: "Food", AlertThresholds: []int{50, 80, 100}}
+	march := models.BudgetPeriod{PeriodStart: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)}
+	progress := func(spent float64) budgeting.Progress {
+		return budgeting.Progress{Amount: 200, Spent: spent, Remaining: 200 - spent, PercentUsed: spent / 2}
+	}
+	subjects := func() []string {
+		var list []string
+		for _, m := range rec.Messages() {
+			list = append(list, m.Subject)
+		}
+		rec.Reset()
+		return list
+	}
+
+	if err := h.raiseBudgetAlerts(store, budget, march, progress(170)); err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(); len(got) != 2 || got[0] != "Food budget reached 50%" || got[1] != "Food budget reached 80%" {
+		t.Errorf("first evaluation sent %v", got)
+	}
+
+	// Thresholds already sent in the period are not sent again.
+	if err := h.raiseBudgetAlerts(store, budget, march, progress(180)); err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(); len(got) != 0 {
+		t.Errorf("same thresholds sent again: %v", got)
+	}
+
+	// A failed delivery is retried on the next evaluation, once.
+	rec.Err = errors.New("smtp down")
+	if err := h.raiseBudgetAlerts(store, budget, march, progress(210)); err != nil {
+		t.Fatal(err)
+	}
+	subjects()
+	rec.Err = nil
+	if err := h.raiseBudgetAlerts(store, budget, march, progress(210)); err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(); len(got) != 1 || got[0] != "Food budget is over by 10.00" {
+		t.Errorf("retry sent %v", got)
+	}
+	if err := h.raiseBudgetAlerts(store, budget, march, progress(210)); err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(); len(got) != 0 {
+		t.Errorf("delivered alert sent again: %v", got)
+	}
+
+	// A new period fires its thresholds afresh.
+	april := models.BudgetPeriod{PeriodStart: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)}
+	if err := h.raiseBudgetAlerts(store, budget, april, progress(120)); err != nil {
+		t.Fatal(err)
+	}
+	if got := subjects(); len(got) != 1 || got[0] != "Food budget reached 50%" {
+		t.Errorf("new period sent %v", got)
+	}
+	if len(store.alerts) != 4 {
+		t.Errorf("recorded %d alerts, want 4", len(store.alerts))
+	}
+}
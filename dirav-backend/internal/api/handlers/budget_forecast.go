@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/forecast"
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type budgetProgressView struct {
+	budgeting.Progress
+	Forecast forecast.Forecast `json:"forecast"`
+}
+
+type budgetForecastEntry struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Period   string `json:"period"`
+	forecast.Forecast
+}
+
+func (h *Handler) forecaster() *forecast.Service {
+	if h.Forecaster == nil {
+		return forecast.New(nil)
+	}
+	return h.Forecaster
+}
+
+// userForecaster is the forecaster counting days in the user's timezone.
+func (h *Handler) userForecaster(userID uuid.UUID) (*forecast.Service, error) {
+	loc, err := userLocation(h.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	return h.forecaster().In(loc), nil
+}
+
+// BudgetForecast projects the end of the current period for every active
+// budget, those heading over first.
+func (h *Handler) BudgetForecast(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var budgets []models.Budget
+	if err := h.DB.Where("user_id = ? AND is_active = true", userID).Order("name").Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	// What a period holds comes from its snapshot, brought up to date as
+	// for progress, so carried and moved money count.
+	svc, err := h.userForecaster(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	now := svc.Clock.Now().In(svc.Location)
+	amounts := make(map[uuid.UUID]float64, len(budgets))
+	for _, b := range budgets {
+		_, periods, err := h.budgetPeriods(userID, b.ID, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		p := periods[len(periods)-1]
+		amounts[b.ID] = p.Budgeted + p.CarriedOver + p.Moved
+	}
+
+	forecasts, err := budgetForecasts(h.DB, svc, userID, budgets, amounts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	out := make([]budgetForecastEntry, len(budgets))
+	for i, b := range budgets {
+		out[i] = budgetForecastEntry{Name: b.Name, Category: b.Category, Period: b.Period, Forecast: forecasts[i]}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].ProjectedPercent > out[j].ProjectedPercent })
+	c.JSON(http.StatusOK, out)
+}
+
+// budgetForecasts projects each budget from one load of the user's
+// expenses, going back as far as the budget needing the most history.
+func budgetForecasts(db *gorm.DB, svc *forecast.Service, userID uuid.UUID, budgets []models.Budget, amounts map[uuid.UUID]float64) ([]forecast.Forecast, error) {
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+	from := svc.HistoryStart(budgets[0])
+	for _, b := range budgets[1:] {
+		if start := svc.HistoryStart(b); start.Before(from) {
+			from = start
+		}
+	}
+
+	var txs []models.Transaction
+	if err := db.Where("user_id = ? AND type = ? AND is_transfer = false", userID, "expense").
+		Where("transaction_date >= ?", from).
+		Order("transaction_date").
+		Find(&txs).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]forecast.Forecast, len(budgets))
+	for i, b := range budgets {
+		start := svc.HistoryStart(b)
+		var own []models.Transaction
+		for _, t := range txs {
+			if !t.TransactionDate.Before(start) && budgetCounts(b, t) {
+				own = append(own, t)
+			}
+		}
+		out[i] = svc.Budget(b, amounts[b.ID], own)
+	}
+	return out, nil
+}
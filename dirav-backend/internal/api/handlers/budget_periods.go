@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errBudgetNotFound     = errors.New("not found")
+	errBudgetEnded        = errors.New("budget has ended")
+	errInsufficientBudget = errors.New("not enough available in budget")
+	errBudgetHasMoves     = errors.New("cannot change the period or start_date of a budget with moves")
+)
+
+type budgetMoveRequest struct {
+	FromBudgetID uuid.UUID `json:"from_budget_id"`
+	ToBudgetID   uuid.UUID `json:"to_budget_id"`
+	Amount       float64   `json:"amount"`
+	Note         string    `json:"note"`
+}
+
+// budgetMoveResult is a move with the two periods it changed.
+type budgetMoveResult struct {
+	models.BudgetMove
+	FromPeriod models.BudgetPeriod `json:"from_period"`
+	ToPeriod   models.BudgetPeriod `json:"to_period"`
+}
+
+// ListBudgetPeriods returns a budget's period snapshots, newest first.
+func (h *Handler) ListBudgetPeriods(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	_, periods, err := h.budgetPeriods(userID, id, now)
+	switch {
+	case errors.Is(err, errBudgetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	for i, j := 0, len(periods)-1; i < j; i, j = i+1, j-1 {
+		periods[i], periods[j] = periods[j], periods[i]
+	}
+	c.JSON(http.StatusOK, periods)
+}
+
+// MoveBudgetMoney moves money from one budget's current period to
+// another's. The source must have the amount available.
+func (h *Handler) MoveBudgetMoney(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req budgetMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.FromBudgetID == uuid.Nil || req.ToBudgetID == uuid.Nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+	if req.FromBudgetID == req.ToBudgetID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot move money to the same budget"})
+		return
+	}
+	amount := roundCents(req.Amount)
+	if amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	var result budgetMoveResult
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var budgets []models.Budget
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ? AND user_id = ?", []uuid.UUID{req.FromBudgetID, req.ToBudgetID}, userID).
+			Order("id").
+			Find(&budgets).Error; err != nil {
+			return err
+		}
+		if len(budgets) != 2 {
+			return errBudgetNotFound
+		}
+
+		current := map[uuid.UUID]models.BudgetPeriod{}
+		for _, b := range budgets {
+			periods, err := syncBudgetPeriods(tx, b, now)
+			if err != nil {
+				return err
+			}
+			p := periods[len(periods)-1]
+			if p.Closed {
+				return errBudgetEnded
+			}
+			current[b.ID] = p
+		}
+
+		from, to := current[req.FromBudgetID], current[req.ToBudgetID]
+		if from.Available < amount {
+			result.FromPeriod = from
+			return errInsufficientBudget
+		}
+		from.Moved = roundCents(from.Moved - amount)
+		to.Moved = roundCents(to.Moved + amount)
+		for _, p := range []*models.BudgetPeriod{&from, &to} {
+			p.Available = budgeting.Available(p.Budgeted, p.CarriedOver, p.Moved, p.Spent)
+			if err := tx.Save(p).Error; err != nil {
+				return err
+			}
+		}
+
+		result.BudgetMove = models.BudgetMove{
+			UserID:       userID,
+			FromBudgetID: req.FromBudgetID,
+			ToBudgetID:   req.ToBudgetID,
+			Amount:       amount,
+			Note:         req.Note,
+		}
+		if err := tx.Create(&result.BudgetMove).Error; err != nil {
+			return err
+		}
+		result.FromPeriod, result.ToPeriod = from, to
+		return nil
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, result)
+	case errors.Is(err, errBudgetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, errBudgetEnded):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errInsufficientBudget):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "available": result.FromPeriod.Available})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	}
+}
+
+// ListBudgetMoves returns the user's moves between budgets, newest first,
+// optionally only those in or out of budget_id.
+func (h *Handler) ListBudgetMoves(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	budgetID, err := queryUUID(c, "budget_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.DB.Where("user_id = ?", userID)
+	if budgetID != nil {
+		query = query.Where("(from_budget_id = ? OR to_budget_id = ?)", *budgetID, *budgetID)
+	}
+
+	var moves []models.BudgetMove
+	if err := query.Order("created_at desc").Find(&moves).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, moves)
+}
+
+// budgetPeriods brings a budget's period snapshots up to the period
+// containing now. They are worked out without a lock, so a read that finds
+// them up to date writes nothing; only when one is missing or out of date
+// is the budget locked and the snapshots saved.
+func (h *Handler) budgetPeriods(userID, id uuid.UUID, now time.Time) (models.Budget, []models.BudgetPeriod, error) {
+	var budget models.Budget
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&budget).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return budget, nil, errBudgetNotFound
+		}
+		return budget, nil, err
+	}
+	plan, err := planBudgetPeriods(h.DB, budget, now)
+	if err != nil || plan.current() {
+		return budget, plan.periods, err
+	}
+
+	var periods []models.BudgetPeriod
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&budget).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errBudgetNotFound
+			}
+			return err
+		}
+
+		var err error
+		periods, err = syncBudgetPeriods(tx, budget, now)
+		return err
+	})
+	return budget, periods, err
+}
+
+// periodPlan is what a budget's snapshots should be: every period up to
+// the current one, oldest first, the indexes of those that differ from
+// what is stored, and the stored snapshots that no longer line up with
+// the budget's periods, after its start date or period changed.
+type periodPlan struct {
+	periods []models.BudgetPeriod
+	changed []int
+	stale   []uuid.UUID
+}
+
+// current reports whether the stored snapshots are up to date.
+func (p periodPlan) current() bool {
+	return len(p.changed) == 0 && len(p.stale) == 0
+}
+
+// planBudgetPeriods works out the budget's snapshots up to the period
+// containing now with budgeting.Snapshots, from live spending, without
+// writing anything. Closed snapshots stand as stored up to the first one
+// that is missing or stale; the rest are worked out from there on.
+func planBudgetPeriods(db *gorm.DB, budget models.Budget, now time.Time) (periodPlan, error) {
+	var list []models.BudgetPeriod
+	if err := db.Where("budget_id = ?", budget.ID).Find(&list).Error; err != nil {
+		return periodPlan{}, err
+	}
+	stored := make(map[string]models.BudgetPeriod, len(list))
+	for _, p := range list {
+		stored[budgeting.DateKey(p.PeriodStart)] = p
+	}
+
+	current := budgeting.At(budget, now)
+	windows := budgeting.Between(budget, budgeting.Nth(budget, 0).Start, current.End)
+
+	var plan periodPlan
+	var prev *models.BudgetPeriod
+	for len(windows) > 1 {
+		key := budgeting.DateKey(windows[0].Start)
+		p, ok := stored[key]
+		if !ok || !p.Closed || p.Stale || !p.PeriodEnd.Equal(windows[0].Last()) {
+			break
+		}
+		delete(stored, key)
+		plan.periods = append(plan.periods, p)
+		prev = &plan.periods[len(plan.periods)-1]
+		windows = windows[1:]
+	}
+
+	spent, err := budgetPeriodSpending(db, budget, windows)
+	if err != nil {
+		return periodPlan{}, err
+	}
+	kept := len(plan.periods)
+	plan.periods = append(plan.periods, budgeting.Snapshots(budget, windows, prev, stored, spent, now)...)
+	for i, w := range windows {
+		key := budgeting.DateKey(w.Start)
+		old, ok := stored[key]
+		delete(stored, key)
+		if !ok || budgeting.SnapshotChanged(old, plan.periods[kept+i]) {
+			plan.changed = append(plan.changed, kept+i)
+		}
+	}
+	for _, p := range stored {
+		plan.stale = append(plan.stale, p.ID)
+	}
+	return plan, nil
+}
+
+// syncBudgetPeriods stores a snapshot for every period of the budget up to
+// the one containing now and returns them oldest first. Only snapshots
+// that changed are written; those that no longer line up with the
+// budget's periods are dropped. The caller must hold a lock on the budget.
+func syncBudgetPeriods(db *gorm.DB, budget models.Budget, now time.Time) ([]models.BudgetPeriod, error) {
+	plan, err := planBudgetPeriods(db, budget, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range plan.changed {
+		if err := db.Save(&plan.periods[i]).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(plan.stale) > 0 {
+		if err := db.Where("id IN ?", plan.stale).Delete(&models.BudgetPeriod{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	return plan.periods, nil
+}
+
+// budgetPeriodSpending totals the budget's spending in each window, keyed
+// by budgeting.DateKey of the window start, in as few queries as
+// budgetWindowSpending allows.
+func budgetPeriodSpending(db *gorm.DB, budget models.Budget, windows []budgeting.Window) (map[string]float64, error) {
+	spent := make(map[string]float64, len(windows))
+	for len(windows) > 0 {
+		n := min(len(windows), maxReportWindows)
+		chunk := make([]budgetWindow, n)
+		for i, w := range windows[:n] {
+			chunk[i] = budgetWindow{budget: budget, window: w}
+		}
+		totals, err := budgetWindowSpending(db, budget.UserID, []models.Budget{budget}, chunk)
+		if err != nil {
+			return nil, err
+		}
+		for key, v := range totals[budget.ID] {
+			spent[key] = v
+		}
+		windows = windows[n:]
+	}
+	return spent, nil
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/categories"
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxReportWindows caps the periods one report query covers, keeping its
+// parameter count well under what Postgres accepts.
+const maxReportWindows = 5000
+
+type budgetReport struct {
+	From    string              `json:"from"`
+	To      string              `json:"to"`
+	Budgets []budgetReportEntry `json:"budgets"`
+}
+
+type budgetReportEntry struct {
+	BudgetID uuid.UUID                `json:"budget_id"`
+	Name     string                   `json:"name"`
+	Category string                   `json:"category"`
+	Period   string                   `json:"period"`
+	Rollover string                   `json:"rollover"`
+	Periods  []budgeting.PeriodReport `json:"periods"`
+	Totals   budgeting.Totals         `json:"totals"`
+}
+
+// budgetWindow is one period of one budget to total spending for.
+type budgetWindow struct {
+	budget models.Budget
+	window budgeting.Window
+}
+
+// BudgetReport compares planned and actual spending for every budget
+// period between from and to, both inclusive. It defaults to the last six
+// months.
+func (h *Handler) BudgetReport(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := time.Date(now.Year(), now.Month()-5, 1, 0, 0, 0, 0, time.UTC)
+	toParam, err := queryDate(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if toParam != nil {
+		to = *toParam
+	}
+	fromParam, err := queryDate(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fromParam != nil {
+		from = *fromParam
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	var budgets []models.Budget
+	if err := h.DB.Where("user_id = ? AND start_date < ?", userID, end).
+		Where("(end_date IS NULL OR end_date >= ?)", from).
+		Order("name").
+		Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	// Budgets that carry money over need their history from the start to
+	// know what the first reported period inherits.
+	windowsByBudget := make(map[uuid.UUID][]budgeting.Window, len(budgets))
+	var all []budgetWindow
+	for _, b := range budgets {
+		first := budgeting.At(b, from)
+		if b.Rollover != "" && b.Rollover != budgeting.RolloverNone {
+			first = budgeting.Nth(b, 0)
+		}
+		ws := budgeting.Between(b, first.Start, end)
+		windowsByBudget[b.ID] = ws
+		for _, w := range ws {
+			all = append(all, budgetWindow{budget: b, window: w})
+		}
+	}
+	if len(all) > maxReportWindows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "range covers too many periods"})
+		return
+	}
+
+	actual, err := budgetWindowSpending(h.DB, userID, budgets, all)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	snapshots, err := budgetSnapshots(h.DB, userID, budgets)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	report := budgetReport{
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Budgets: make([]budgetReportEntry, 0, len(budgets)),
+	}
+	for _, b := range budgets {
+		rows := budgeting.History(b, windowsByBudget[b.ID], snapshots[b.ID], actual[b.ID], from)
+		if rows == nil {
+			rows = []budgeting.PeriodReport{}
+		}
+		report.Budgets = append(report.Budgets, budgetReportEntry{
+			BudgetID: b.ID,
+			Name:     b.Name,
+			Category: b.Category,
+			Period:   b.Period,
+			Rollover: b.Rollover,
+			Periods:  rows,
+			Totals:   budgeting.Total(rows),
+		})
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// budgetWindowSpending totals the spending of every window in one grouped
+// query. Budgets and windows go in as VALUES lists, joined to the
+// transactions that count against each budget: expenses in its category
+// or a child category, on its account when it has one. Transfers between
+// the user's own accounts are not spending. The result is keyed by
+// budget, then by budgeting.DateKey of the window start.
+func budgetWindowSpending(db *gorm.DB, userID uuid.UUID, budgets []models.Budget, windows []budgetWindow) (map[uuid.UUID]map[string]float64, error) {
+	out := make(map[uuid.UUID]map[string]float64, len(budgets))
+	if len(windows) == 0 {
+		return out, nil
+	}
+
+	var q strings.Builder
+	args := make([]interface{}, 0, len(budgets)*4+len(windows)*3+1)
+
+	q.WriteString("WITH b (budget_id, category, pattern, account_id) AS (VALUES ")
+	for i, bud := range budgets {
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString("(?::uuid, ?, ?, ?::uuid)")
+		category := strings.TrimSpace(bud.Category)
+		args = append(args, bud.ID, category, escapeLike(category+categories.Separator)+"%", bud.AccountID)
+	}
+	q.WriteString("), w (budget_id, period_start, period_end) AS (VALUES ")
+	for i, bw := range windows {
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString("(?::uuid, ?::timestamptz, ?::timestamptz)")
+		args = append(args, bw.budget.ID, bw.window.Start, bw.window.End)
+	}
+	q.WriteString(`)
+SELECT w.budget_id, w.period_start, COALESCE(SUM(t.amount), 0) AS actual
+FROM w
+JOIN b ON b.budget_id = w.budget_id
+LEFT JOIN transactions t ON t.user_id = ?
+	AND t.type = 'expense'
+	AND t.is_transfer = false
+	AND t.transaction_date >= w.period_start
+	AND t.transaction_date < w.period_end
+	AND (b.category = '' OR lower(t.category) = lower(b.category) OR lower(t.category) LIKE lower(b.pattern))
+	AND (b.account_id IS NULL OR t.account_id = b.account_id)
+GROUP BY w.budget_id, w.period_start`)
+	args = append(args, userID)
+
+	var rows []struct {
+		BudgetID    uuid.UUID
+		PeriodStart time.Time
+		Actual      float64
+	}
+	if err := db.Raw(q.String(), args...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		if out[r.BudgetID] == nil {
+			out[r.BudgetID] = map[string]float64{}
+		}
+		out[r.BudgetID][budgeting.DateKey(r.PeriodStart)] = r.Actual
+	}
+	return out, nil
+}
+
+// budgetSnapshots loads the stored periods of the budgets, keyed by budget
+// and then by budgeting.DateKey of the period start.
+func budgetSnapshots(db *gorm.DB, userID uuid.UUID, budgets []models.Budget) (map[uuid.UUID]map[string]models.BudgetPeriod, error) {
+	out := make(map[uuid.UUID]map[string]models.BudgetPeriod, len(budgets))
+	if len(budgets) == 0 {
+		return out, nil
+	}
+	ids := make([]uuid.UUID, len(budgets))
+	for i, b := range budgets {
+		ids[i] = b.ID
+	}
+
+	var periods []models.BudgetPeriod
+	if err := db.Where("user_id = ? AND budget_id IN ?", userID, ids).Find(&periods).Error; err != nil {
+		return nil, err
+	}
+	for _, p := range periods {
+		if out[p.BudgetID] == nil {
+			out[p.BudgetID] = map[string]models.BudgetPeriod{}
+		}
+		out[p.BudgetID][budgeting.DateKey(p.PeriodStart)] = p
+	}
+	return out, nil
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/templates"
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type fromTemplateRequest struct {
+	Template string `json:"template"`
+	// Allowance defaults to the user's monthly allowance.
+	Allowance *float64 `json:"allowance"`
+	// StartDate defaults to the first day of the current month.
+	StartDate string `json:"start_date"`
+}
+
+type budgetTemplateRequest struct {
+	Slug        string                      `json:"slug"`
+	Name        string                      `json:"name"`
+	Description string                      `json:"description"`
+	Allowance   float64                     `json:"allowance"`
+	Budgets     []budgetTemplateItemRequest `json:"budgets"`
+	IsActive    *bool                       `json:"is_active"`
+}
+
+type budgetTemplateItemRequest struct {
+	Name     string  `json:"name"`
+	Category string  `json:"category"`
+	Amount   float64 `json:"amount"`
+	Period   string  `json:"period"`
+	Rollover string  `json:"rollover"`
+}
+
+// ListBudgetTemplates returns the active budget templates.
+func (h *Handler) ListBudgetTemplates(c *gin.Context) {
+	if _, err := getUserID(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var list []models.BudgetTemplate
+	if err := h.DB.Where("is_active = true").Order("name").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateBudgetsFromTemplate creates the budgets of a template, scaled from
+// the template's allowance to the user's.
+func (h *Handler) CreateBudgetsFromTemplate(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req fromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.Template == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	startDate := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if req.StartDate != "" {
+		if startDate, err = time.Parse("2006-01-02", req.StartDate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date"})
+			return
+		}
+	}
+
+	allowance := req.Allowance
+	if allowance == nil {
+		var user models.User
+		if err := h.DB.First(&user, "id = ?", userID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		allowance = user.MonthlyAllowance
+	}
+	if allowance == nil || *allowance <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "allowance must be positive"})
+		return
+	}
+
+	var tpl models.BudgetTemplate
+	if err := h.DB.Where("slug = ? AND is_active = true", req.Template).First(&tpl).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+
+	items := templates.Scale(tpl, *allowance)
+	budgets := make([]models.Budget, len(items))
+	for i, it := range items {
+		budgets[i] = models.Budget{
+			UserID:    userID,
+			Name:      it.Name,
+			Amount:    it.Amount,
+			Period:    it.Period,
+			Category:  it.Category,
+			StartDate: startDate,
+			IsActive:  true,
+			Rollover:  it.Rollover,
+		}
+	}
+	if err := h.DB.Create(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, budgets)
+}
+
+// RequireAdmin lets only admin users through.
+func (h *Handler) RequireAdmin(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Select("is_admin").First(&user, "id = ?", userID).Error; err != nil || !user.IsAdmin {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+	c.Next()
+}
+
+// AdminListBudgetTemplates returns every template, inactive ones included.
+func (h *Handler) AdminListBudgetTemplates(c *gin.Context) {
+	var list []models.BudgetTemplate
+	if err := h.DB.Order("name").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *Handler) AdminCreateBudgetTemplate(c *gin.Context) {
+	var req budgetTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	tpl := req.template()
+	tpl.IsActive = req.IsActive == nil || *req.IsActive
+	if err := templates.Validate(tpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Create(&tpl).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "slug already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tpl)
+}
+
+// AdminUpdateBudgetTemplate replaces a template. The slug in the body, when
+// given, renames it.
+func (h *Handler) AdminUpdateBudgetTemplate(c *gin.Context) {
+	var existing models.BudgetTemplate
+	if err := h.DB.Where("slug = ?", c.Param("slug")).First(&existing).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	var req budgetTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.Slug == "" {
+		req.Slug = existing.Slug
+	}
+
+	tpl := req.template()
+	tpl.ID = existing.ID
+	tpl.CreatedAt = existing.CreatedAt
+	tpl.IsActive = existing.IsActive
+	if req.IsActive != nil {
+		tpl.IsActive = *req.IsActive
+	}
+	if err := templates.Validate(tpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Save(&tpl).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "slug already in use"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tpl)
+}
+
+// AdminDeleteBudgetTemplate removes a template. A deleted built-in comes
+// back at the next start; deactivate it to hide it for good.
+func (h *Handler) AdminDeleteBudgetTemplate(c *gin.Context) {
+	if err := h.DB.Where("slug = ?", c.Param("slug")).Delete(&models.BudgetTemplate{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func (r budgetTemplateRequest) template() models.BudgetTemplate {
+	tpl := models.BudgetTemplate{
+		Slug:        r.Slug,
+		Name:        r.Name,
+		Description: r.Description,
+		Allowance:   r.Allowance,
+		Budgets:     make([]models.BudgetTemplateItem, len(r.Budgets)),
+	}
+	for i, it := range r.Budgets {
+		tpl.Budgets[i] = models.BudgetTemplateItem{
+			Name:     it.Name,
+			Category: it.Category,
+			Amount:   it.Amount,
+			Period:   it.Period,
+			Rollover: it.Rollover,
+		}
+	}
+	templates.Normalize(&tpl)
+	return tpl
+}
+
+// isUniqueViolation reports whether err is Postgres refusing a duplicate
+// key.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -1,22 +1,42 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
+	"dirav-backend/internal/budgeting"
 	"dirav-backend/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type budgetRequest struct {
-	Name      string  `json:"name"`
-	Amount    float64 `json:"amount"`
-	Period    string  `json:"period"`
-	Category  string  `json:"category"`
-	StartDate string  `json:"start_date"`
-	EndDate   string  `json:"end_date"`
-	IsActive  bool    `json:"is_active"`
+	Name      string     `json:"name"`
+	Amount    float64    `json:"amount"`
+	Period    string     `json:"period"`
+	Category  string     `json:"category"`
+	AccountID *uuid.UUID `json:"account_id"`
+	StartDate string     `json:"start_date"`
+	EndDate   string     `json:"end_date"`
+	IsActive  bool       `json:"is_active"`
+	Rollover  string     `json:"rollover"`
+	// AlertThresholds left out keeps the current thresholds; an empty
+	// list turns alerts off.
+	AlertThresholds []int `json:"alert_thresholds"`
+}
+
+var budgetList = listSpec{
+	sorts: map[string]sortField{
+		"name":       {column: "name", field: "Name", kind: "text"},
+		"amount":     {column: "amount", field: "Amount", kind: "number"},
+		"start_date": {column: "start_date", field: "StartDate", kind: "time"},
+		"created_at": {column: "created_at", field: "CreatedAt", kind: "time"},
+	},
+	defaultSort: "-created_at",
 }
 
 func (h *Handler) ListBudgets(c *gin.Context) {
@@ -38,13 +58,14 @@ func (h *Handler) ListBudgets(c *gin.Context) {
 			query = query.Where("is_active = false")
 		}
 	}
-
-	var budgets []models.Budget
-	if err := query.Order("created_at desc").Find(&budgets).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
-		return
+	if cat := c.Query("category"); cat != "" {
+		query = query.Where("category = ?", cat)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("name ILIKE ?", likePattern(q))
 	}
-	c.JSON(http.StatusOK, budgets)
+
+	listPage[models.Budget](c, query, budgetList)
 }
 
 func (h *Handler) CreateBudget(c *gin.Context) {
@@ -60,6 +81,27 @@ func (h *Handler) CreateBudget(c *gin.Context) {
 		return
 	}
 
+	if !budgeting.ValidPeriod(req.Period) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid period"})
+		return
+	}
+	if req.Rollover == "" {
+		req.Rollover = budgeting.RolloverNone
+	}
+	if !budgeting.ValidRollover(req.Rollover) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rollover"})
+		return
+	}
+	if req.AlertThresholds != nil {
+		if req.AlertThresholds, err = budgeting.NormalizeThresholds(req.AlertThresholds); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !h.accountValid(c, userID, req.AccountID) {
+		return
+	}
+
 	startDate, err := time.Parse("2006-01-02", req.StartDate)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date"})
@@ -77,14 +119,17 @@ func (h *Handler) CreateBudget(c *gin.Context) {
 	}
 
 	budget := models.Budget{
-		UserID:    userID,
-		Name:      req.Name,
-		Amount:    req.Amount,
-		Period:    req.Period,
-		Category:  req.Category,
-		StartDate: startDate,
-		EndDate:   endDate,
-		IsActive:  req.IsActive,
+		UserID:          userID,
+		Name:            req.Name,
+		Amount:          req.Amount,
+		Period:          req.Period,
+		Category:        req.Category,
+		AccountID:       req.AccountID,
+		StartDate:       startDate,
+		EndDate:         endDate,
+		IsActive:        req.IsActive,
+		Rollover:        req.Rollover,
+		AlertThresholds: req.AlertThresholds,
 	}
 
 	if err := h.DB.Create(&budget).Error; err != nil {
@@ -136,12 +181,35 @@ func (h *Handler) UpdateBudget(c *gin.Context) {
 		return
 	}
 
+	if !budgeting.ValidPeriod(req.Period) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid period"})
+		return
+	}
+	if req.Rollover == "" {
+		req.Rollover = budgeting.RolloverNone
+	}
+	if !budgeting.ValidRollover(req.Rollover) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid rollover"})
+		return
+	}
+	if req.AlertThresholds != nil {
+		if req.AlertThresholds, err = budgeting.NormalizeThresholds(req.AlertThresholds); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !h.accountValid(c, userID, req.AccountID) {
+		return
+	}
+
 	updates := map[string]interface{}{
-		"name":      req.Name,
-		"amount":    req.Amount,
-		"period":    req.Period,
-		"category":  req.Category,
-		"is_active": req.IsActive,
+		"name":       req.Name,
+		"amount":     req.Amount,
+		"period":     req.Period,
+		"category":   req.Category,
+		"account_id": req.AccountID,
+		"is_active":  req.IsActive,
+		"rollover":   req.Rollover,
 	}
 
 	if req.StartDate != "" {
@@ -162,14 +230,54 @@ func (h *Handler) UpdateBudget(c *gin.Context) {
 		updates["end_date"] = endDate
 	}
 
-	if err := h.DB.Model(&models.Budget{}).
-		Where("id = ? AND user_id = ?", id, userID).
-		Updates(updates).Error; err != nil {
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var budget models.Budget
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&budget).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errBudgetNotFound
+			}
+			return err
+		}
+
+		// Moves live in the snapshots of the periods they were made in,
+		// which a new period or start date would drop.
+		start, ok := updates["start_date"].(time.Time)
+		if req.Period != budget.Period || ok && !start.Equal(budget.StartDate) {
+			var moves int64
+			if err := tx.Model(&models.BudgetMove{}).
+				Where("from_budget_id = ? OR to_budget_id = ?", id, id).
+				Count(&moves).Error; err != nil {
+				return err
+			}
+			if moves > 0 {
+				return errBudgetHasMoves
+			}
+		}
+
+		if err := tx.Model(&budget).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Thresholds go through the JSON serializer, which only runs for
+		// struct updates.
+		if req.AlertThresholds != nil {
+			return tx.Model(&budget).
+				Select("alert_thresholds").
+				Updates(&models.Budget{AlertThresholds: req.AlertThresholds}).Error
+		}
+		return nil
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"status": "updated"})
+	case errors.Is(err, errBudgetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, errBudgetHasMoves):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
-		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"status": "updated"})
 }
 
 func (h *Handler) DeleteBudget(c *gin.Context) {
@@ -185,7 +293,20 @@ func (h *Handler) DeleteBudget(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Budget{}).Error; err != nil {
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Budget{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if err := tx.Where("budget_id = ?", id).Delete(&models.BudgetAlert{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("budget_id = ?", id).Delete(&models.PlanAllocation{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("budget_id = ?", id).Delete(&models.BudgetPeriod{}).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
@@ -206,31 +327,59 @@ func (h *Handler) BudgetProgress(c *gin.Context) {
 		return
 	}
 
-	var budget models.Budget
-	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&budget).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	svc, err := h.userForecaster(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
-
-	start := budget.StartDate
-	end := time.Now()
-	if budget.EndDate != nil {
-		end = *budget.EndDate
+	now := svc.Clock.Now().In(svc.Location)
+	budget, periods, err := h.budgetPeriods(userID, id, now)
+	switch {
+	case errors.Is(err, errBudgetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
 	}
 
-	var total float64
-	if err := h.DB.Model(&models.Transaction{}).
-		Where("user_id = ? AND type = ? AND transaction_date >= ? AND transaction_date <= ?", userID, "expense", start, end).
-		Select("COALESCE(SUM(amount), 0)").
-		Scan(&total).Error; err != nil {
+	progress := budgetProgress(budget, periods[len(periods)-1], now)
+	forecasts, err := budgetForecasts(h.DB, svc, userID, []models.Budget{budget}, map[uuid.UUID]float64{budget.ID: progress.Amount})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
+	c.JSON(http.StatusOK, budgetProgressView{Progress: progress, Forecast: forecasts[0]})
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"budget_id": budget.ID.String(),
-		"amount":    budget.Amount,
-		"spent":     total,
-		"remaining": budget.Amount - total,
-	})
+// budgetProgress reports a period snapshot as progress: the amount is
+// everything the period holds, its own budget plus what was carried over
+// and moved in.
+func budgetProgress(budget models.Budget, p models.BudgetPeriod, now time.Time) budgeting.Progress {
+	w := budgeting.At(budget, p.PeriodStart)
+	progress := budgeting.NewProgress(budget.ID, p.Budgeted+p.CarriedOver+p.Moved, p.Spent, w, now)
+	progress.Budgeted = p.Budgeted
+	progress.CarriedOver = p.CarriedOver
+	progress.Moved = p.Moved
+	return progress
+}
+
+// accountValid checks that an optional account belongs to the user,
+// writing the error response when it does not.
+func (h *Handler) accountValid(c *gin.Context, userID uuid.UUID, accountID *uuid.UUID) bool {
+	if accountID == nil {
+		return true
+	}
+	var count int64
+	if err := h.DB.Model(&models.Account{}).
+		Where("id = ? AND user_id = ?", *accountID, userID).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+		return false
+	}
+	return true
 }
@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/savings"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Schedule and run states.
+const (
+	scheduleActive    = "active"
+	schedulePaused    = "paused"
+	scheduleCompleted = "completed"
+
+	runSucceeded = "succeeded"
+	runSkipped   = "skipped"
+	runFailed    = "failed"
+)
+
+var errScheduleState = errors.New("schedule is not in the expected state")
+
+type scheduleRequest struct {
+	Amount           float64    `json:"amount"`
+	Cadence          string     `json:"cadence"`
+	AccountID        *uuid.UUID `json:"account_id"`
+	SavingsAccountID *uuid.UUID `json:"savings_account_id"`
+	// StartDate is the first run; it defaults to today.
+	StartDate string `json:"start_date"`
+	// StopWhenCompleted defaults to true.
+	StopWhenCompleted *bool  `json:"stop_when_completed"`
+	Note              string `json:"note"`
+}
+
+// ListContributionSchedules returns the user's contribution schedules for a
+// goal.
+func (h *Handler) ListContributionSchedules(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var schedules []models.ContributionSchedule
+	if err := h.DB.Where("savings_goal_id = ? AND user_id = ?", id, userID).
+		Order("created_at").
+		Find(&schedules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+func (h *Handler) CreateContributionSchedule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req scheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.AccountID == nil || req.Cadence == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+	if !savings.ValidAmount(req.Amount) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": savings.ErrInvalidAmount.Error()})
+		return
+	}
+	if !savings.ValidCadence(req.Cadence) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cadence must be weekly, biweekly or monthly"})
+		return
+	}
+	if req.SavingsAccountID != nil && *req.SavingsAccountID == *req.AccountID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account_id and savings_account_id must differ"})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if req.StartDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date"})
+			return
+		}
+		if parsed.Before(start) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "start_date cannot be in the past"})
+			return
+		}
+		start = parsed
+	}
+
+	if _, _, ok := h.goalFor(c, userID, id, savings.CanContribute); !ok {
+		return
+	}
+	if !h.accountValid(c, userID, req.AccountID) || !h.accountValid(c, userID, req.SavingsAccountID) {
+		return
+	}
+
+	schedule := models.ContributionSchedule{
+		UserID:            userID,
+		SavingsGoalID:     id,
+		AccountID:         *req.AccountID,
+		SavingsAccountID:  req.SavingsAccountID,
+		Amount:            req.Amount,
+		Cadence:           req.Cadence,
+		StartDate:         start,
+		NextRunDate:       start,
+		StopWhenCompleted: req.StopWhenCompleted == nil || *req.StopWhenCompleted,
+		Status:            scheduleActive,
+		Note:              req.Note,
+	}
+	if err := h.DB.Create(&schedule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// PauseContributionSchedule stops a schedule from running until resumed.
+func (h *Handler) PauseContributionSchedule(c *gin.Context) {
+	h.setScheduleStatus(c, scheduleActive, schedulePaused)
+}
+
+// ResumeContributionSchedule restarts a paused schedule from its next run
+// date on or after today; runs missed while paused are not made up.
+func (h *Handler) ResumeContributionSchedule(c *gin.Context) {
+	h.setScheduleStatus(c, schedulePaused, scheduleActive)
+}
+
+func (h *Handler) setScheduleStatus(c *gin.Context, from, to string) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	var schedule models.ContributionSchedule
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", id, userID).
+			First(&schedule).Error; err != nil {
+			return err
+		}
+		if schedule.Status != from {
+			return errScheduleState
+		}
+
+		updates := map[string]interface{}{"status": to}
+		if to == scheduleActive {
+			schedule.NextRunDate = savings.NextRun(schedule.StartDate, schedule.Cadence, now)
+			updates["next_run_date"] = schedule.NextRunDate
+		}
+		schedule.Status = to
+		return tx.Model(&schedule).Updates(updates).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, errScheduleState):
+		c.JSON(http.StatusConflict, gin.H{"error": "schedule is " + schedule.Status})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *Handler) DeleteContributionSchedule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ContributionSchedule{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return tx.Where("schedule_id = ?", id).Delete(&models.ContributionRun{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListContributionRuns returns a schedule's runs, newest first.
+func (h *Handler) ListContributionRuns(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var runs []models.ContributionRun
+	if err := h.DB.Where("schedule_id = ? AND user_id = ?", id, userID).
+		Order("run_date desc").
+		Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// RunSavingsWorker runs due contribution schedules and sweeps round-ups
+// into their goals, now and then every interval until ctx is done.
+func (h *Handler) RunSavingsWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.runDueContributions(time.Now())
+		h.sweepRoundUps(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDueContributions catches every active schedule up to today, one run
+// date at a time. A schedule that fails on a database error is left where
+// it is and retried on the next pass.
+func (h *Handler) runDueContributions(now time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var due []models.ContributionSchedule
+	if err := h.DB.Where("status = ? AND next_run_date <= ?", scheduleActive, today).
+		Order("next_run_date").
+		Find(&due).Error; err != nil {
+		log.Printf("contribution schedules: %v", err)
+		return
+	}
+	for _, s := range due {
+		for s.Status == scheduleActive && !s.NextRunDate.After(today) {
+			next, err := h.runContributionSchedule(s.ID, s.NextRunDate)
+			if err != nil {
+				log.Printf("contribution schedule %s: %v", s.ID, err)
+				break
+			}
+			s = next
+		}
+	}
+}
+
+// runContributionSchedule makes the schedule's run for date, records it and
+// moves the schedule to its next run date, all in one transaction. The
+// schedule row is locked and the run date is unique per schedule, so a run
+// another worker already made is not made again. A run that cannot go
+// ahead, because the goal is reached or an account is gone, is recorded
+// as skipped or failed rather than retried.
+func (h *Handler) runContributionSchedule(id uuid.UUID, date time.Time) (models.ContributionSchedule, error) {
+	var s models.ContributionSchedule
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&s, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if s.Status != scheduleActive || !s.NextRunDate.Equal(date) {
+			return nil
+		}
+
+		run := models.ContributionRun{UserID: s.UserID, ScheduleID: s.ID, RunDate: date, Status: runSucceeded}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&run)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			if err := h.makeScheduledContribution(tx, &s, &run); err != nil {
+				return err
+			}
+			if err := tx.Model(&run).Updates(map[string]interface{}{
+				"status":          run.Status,
+				"contribution_id": run.ContributionID,
+				"amount":          run.Amount,
+				"error":           run.Error,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		s.NextRunDate = savings.NextRun(s.StartDate, s.Cadence, date.AddDate(0, 0, 1))
+		return tx.Model(&s).Updates(map[string]interface{}{
+			"status":        s.Status,
+			"next_run_date": s.NextRunDate,
+		}).Error
+	})
+	return s, err
+}
+
+// makeScheduledContribution contributes for one run, filling in the run's
+// outcome. It completes the schedule once the goal is reached, when the
+// schedule stops there, and pauses it when an account has gone or the user
+// may no longer contribute to the goal.
+func (h *Handler) makeScheduledContribution(tx *gorm.DB, s *models.ContributionSchedule, run *models.ContributionRun) error {
+	var goal models.SavingsGoal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&goal, "id = ?", s.SavingsGoalID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			run.Status, run.Error = runFailed, "savings goal not found"
+			s.Status = schedulePaused
+			return nil
+		}
+		return err
+	}
+	role, err := goalRole(tx, goal, s.UserID)
+	if err != nil {
+		return err
+	}
+	if !savings.CanContribute(role) {
+		run.Status, run.Error = runFailed, "no longer allowed to contribute"
+		s.Status = schedulePaused
+		return nil
+	}
+	if s.StopWhenCompleted && savings.Completed(goal) {
+		run.Status, run.Error = runSkipped, "savings goal completed"
+		s.Status = scheduleCompleted
+		return nil
+	}
+
+	accounts := []uuid.UUID{s.AccountID}
+	if s.SavingsAccountID != nil {
+		accounts = append(accounts, *s.SavingsAccountID)
+	}
+	var count int64
+	if err := tx.Model(&models.Account{}).Where("id IN ? AND user_id = ?", accounts, s.UserID).Count(&count).Error; err != nil {
+		return err
+	}
+	if int(count) != len(accounts) {
+		run.Status, run.Error = runFailed, "account not found"
+		s.Status = schedulePaused
+		return nil
+	}
+
+	note := s.Note
+	if note == "" {
+		note = "Scheduled contribution"
+	}
+	accountID := s.AccountID
+	contribution := models.SavingsContribution{
+		UserID:           s.UserID,
+		SavingsGoalID:    s.SavingsGoalID,
+		AccountID:        &accountID,
+		SavingsAccountID: s.SavingsAccountID,
+		Amount:           savings.Capped(goal, s.Amount, s.StopWhenCompleted),
+		Date:             run.RunDate,
+		Note:             note,
+	}
+	goal, err = h.saveContribution(tx, &contribution)
+	if err != nil {
+		return err
+	}
+
+	run.ContributionID = &contribution.ID
+	run.Amount = contribution.Amount
+	if goal.IsCompleted && s.StopWhenCompleted {
+		s.Status = scheduleCompleted
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dirav-backend/internal/duplicates"
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	duplicateDefaultDays  = 3
+	duplicateMaxDays      = 14
+	duplicateDefaultScore = 0.5
+	duplicateMaxLimit     = 200
+	// duplicateMaxCandidates caps the pairs the database hands back for
+	// scoring, so a pathological history cannot blow up the request.
+	duplicateMaxCandidates = 5000
+)
+
+var (
+	errMergeNotFound      = errors.New("not found")
+	errMergeSelf          = errors.New("cannot merge a transaction into itself")
+	errMergeAccount       = errors.New("transactions belong to different accounts")
+	errMergeMissingTarget = errors.New("missing duplicate_ids")
+)
+
+type dismissDuplicateRequest struct {
+	FirstID  uuid.UUID `json:"first_id"`
+	SecondID uuid.UUID `json:"second_id"`
+}
+
+type mergeTransactionsRequest struct {
+	DuplicateIDs []uuid.UUID `json:"duplicate_ids"`
+}
+
+// ListDuplicates is the review queue of likely duplicate pairs: same
+// account, type and amount, dated at most `days` apart, scored on title
+// similarity and date distance. Dismissed pairs are left out.
+func (h *Handler) ListDuplicates(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	days := duplicateDefaultDays
+	if raw := c.Query("days"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 || v > duplicateMaxDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 0 and 14"})
+			return
+		}
+		days = v
+	}
+	minScore := duplicateDefaultScore
+	if raw := c.Query("min_score"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || v > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_score must be between 0 and 1"})
+			return
+		}
+		minScore = v
+	}
+	limit := 50
+	if raw := c.Query("limit"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v > 0 {
+			limit = v
+		}
+	}
+	if limit > duplicateMaxLimit {
+		limit = duplicateMaxLimit
+	}
+
+	query := h.DB.Table("transactions AS a").
+		Select("a.id AS first_id, b.id AS second_id").
+		Joins(`JOIN transactions AS b ON b.user_id = a.user_id AND a.id < b.id
+			AND b.type = a.type AND b.amount = a.amount
+			AND b.account_id IS NOT DISTINCT FROM a.account_id
+			AND b.transaction_date BETWEEN a.transaction_date - make_interval(days => ?) AND a.transaction_date + make_interval(days => ?)`, days, days).
+		Where("a.user_id = ?", userID).
+		Where("NOT (a.status = ? AND b.status = ?)", "reconciled", "reconciled").
+		Where(`NOT EXISTS (SELECT 1 FROM duplicate_dismissals d WHERE d.user_id = a.user_id
+			AND ((d.first_id = a.id AND d.second_id = b.id) OR (d.first_id = b.id AND d.second_id = a.id)))`)
+	if raw := c.Query("account_id"); raw != "" {
+		accountID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+			return
+		}
+		query = query.Where("a.account_id = ?", accountID)
+	}
+
+	var candidates []struct {
+		FirstID  uuid.UUID
+		SecondID uuid.UUID
+	}
+	if err := query.Limit(duplicateMaxCandidates).Scan(&candidates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, 2*len(candidates))
+	for _, p := range candidates {
+		ids = append(ids, p.FirstID, p.SecondID)
+	}
+	byID := map[uuid.UUID]models.Transaction{}
+	if len(ids) > 0 {
+		var txs []models.Transaction
+		if err := h.DB.Where("user_id = ? AND id IN ?", userID, ids).Find(&txs).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		for _, t := range txs {
+			byID[t.ID] = t
+		}
+	}
+
+	pairs := []duplicates.Pair{}
+	for _, p := range candidates {
+		a, b := byID[p.FirstID], byID[p.SecondID]
+		if b.TransactionDate.Before(a.TransactionDate) {
+			a, b = b, a
+		}
+		pair, ok := duplicates.Score(a, b, days)
+		if ok && pair.Score >= minScore {
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Score != pairs[j].Score {
+			return pairs[i].Score > pairs[j].Score
+		}
+		return pairs[i].B.TransactionDate.After(pairs[j].B.TransactionDate)
+	})
+
+	total := len(pairs)
+	if len(pairs) > limit {
+		pairs = pairs[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{"total": total, "pairs": pairs})
+}
+
+// DismissDuplicate marks a pair as reviewed and distinct.
+func (h *Handler) DismissDuplicate(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dismissDuplicateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.FirstID == uuid.Nil || req.SecondID == uuid.Nil || req.FirstID == req.SecondID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+
+	var count int64
+	if err := h.DB.Model(&models.Transaction{}).
+		Where("user_id = ? AND id IN ?", userID, []uuid.UUID{req.FirstID, req.SecondID}).
+		Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if count != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	// Store the pair in a fixed order so the unique index catches repeats.
+	first, second := req.FirstID, req.SecondID
+	if second.String() < first.String() {
+		first, second = second, first
+	}
+	dismissal := models.DuplicateDismissal{UserID: userID, FirstID: first, SecondID: second}
+	if err := h.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&dismissal).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "dismissed"})
+}
+
+// MergeTransactions folds the duplicates into the transaction in the path
+// and deletes them. Tags and attachments are combined, distinct notes are
+// appended, and empty fields are filled from the duplicates. The account
+// balance ends up counting the payment once.
+func (h *Handler) MergeTransactions(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req mergeTransactionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	var keep models.Transaction
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		keep, err = mergeTransactions(tx, userID, id, req.DuplicateIDs)
+		return err
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, keep)
+	case errors.Is(err, errMergeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, errMergeSelf), errors.Is(err, errMergeAccount), errors.Is(err, errMergeMissingTarget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errTransactionLocked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	}
+}
+
+func mergeTransactions(db *gorm.DB, userID, keepID uuid.UUID, dupIDs []uuid.UUID) (models.Transaction, error) {
+	var keep models.Transaction
+	if len(dupIDs) == 0 {
+		return keep, errMergeMissingTarget
+	}
+	unique := map[uuid.UUID]bool{}
+	for _, id := range dupIDs {
+		if id == keepID {
+			return keep, errMergeSelf
+		}
+		unique[id] = true
+	}
+
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", keepID, userID).
+		First(&keep).Error; err != nil {
+		return keep, errMergeNotFound
+	}
+	var dups []models.Transaction
+	if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ? AND user_id = ?", dupIDs, userID).
+		Order("transaction_date, created_at").
+		Find(&dups).Error; err != nil {
+		return keep, err
+	}
+	if len(dups) != len(unique) {
+		return keep, errMergeNotFound
+	}
+	if keep.Status == "reconciled" {
+		return keep, errTransactionLocked
+	}
+	for _, d := range dups {
+		if d.Status == "reconciled" {
+			return keep, errTransactionLocked
+		}
+	}
+
+	for _, d := range dups {
+		if !sameAccountID(keep.AccountID, d.AccountID) {
+			return keep, errMergeAccount
+		}
+
+		// Only imported rows moved the balance. If the kept row did not, it
+		// takes over the first imported duplicate's place in its batch;
+		// every other imported duplicate is taken out of the balance and
+		// out of its batch.
+		if d.ImportBatchID != nil {
+			if keep.ImportBatchID == nil {
+				keep.ImportBatchID = d.ImportBatchID
+				if err := moveBatchAmount(db, *d.ImportBatchID, *d.AccountID, signedAmount(keep)-signedAmount(d), 0); err != nil {
+					return keep, err
+				}
+			} else if err := moveBatchAmount(db, *d.ImportBatchID, *d.AccountID, -signedAmount(d), -1); err != nil {
+				return keep, err
+			}
+		}
+
+		keep.Tags = mergeStrings(keep.Tags, d.Tags, true)
+		keep.Attachments = mergeStrings(keep.Attachments, d.Attachments, false)
+		if d.Notes != "" && !strings.Contains(keep.Notes, d.Notes) {
+			if keep.Notes != "" {
+				keep.Notes += "\n"
+			}
+			keep.Notes += d.Notes
+		}
+		if keep.Category == "" {
+			keep.Category = d.Category
+		}
+		if keep.Merchant == "" {
+			keep.Merchant = d.Merchant
+		}
+		if keep.Counterparty == "" {
+			keep.Counterparty = d.Counterparty
+		}
+		if keep.ValueDate == nil {
+			keep.ValueDate = d.ValueDate
+		}
+		// Keeping the bank reference lets re-imports recognize the line.
+		if keep.ExternalID == "" {
+			keep.ExternalID = d.ExternalID
+		}
+		keep.IsTransfer = keep.IsTransfer || d.IsTransfer
+		if d.Status == "cleared" {
+			keep.Status = "cleared"
+		}
+	}
+
+	if err := db.Model(&keep).
+		Select("tags", "attachments", "notes", "category", "merchant", "counterparty",
+			"value_date", "external_id", "is_transfer", "import_batch_id", "status").
+		Updates(&keep).Error; err != nil {
+		return keep, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(dups))
+	for _, d := range dups {
+		ids = append(ids, d.ID)
+	}
+	if err := db.Where("id IN ?", ids).Delete(&models.Transaction{}).Error; err != nil {
+		return keep, err
+	}
+	if err := db.Where("first_id IN ? OR second_id IN ?", ids, ids).
+		Delete(&models.DuplicateDismissal{}).Error; err != nil {
+		return keep, err
+	}
+	return keep, nil
+}
+
+// moveBatchAmount shifts an import batch's net amount and row count along
+// with the balance of its account, so undoing the batch later reverses
+// exactly what is still there.
+func moveBatchAmount(db *gorm.DB, batchID, accountID uuid.UUID, delta float64, rows int) error {
+	if delta == 0 && rows == 0 {
+		return nil
+	}
+	if err := db.Model(&models.ImportBatch{}).
+		Where("id = ?", batchID).
+		Updates(map[string]interface{}{
+			"net_amount": gorm.Expr("net_amount + ?", delta),
+			"row_count":  gorm.Expr("row_count + ?", rows),
+		}).Error; err != nil {
+		return err
+	}
+
+	return adjustBalance(db, accountID, delta)
+}
+
+// mergeStrings appends the values of more that list lacks, ignoring case
+// when foldCase is set.
+func mergeStrings(list, more []string, foldCase bool) []string {
+	for _, v := range more {
+		found := false
+		for _, w := range list {
+			if v == w || (foldCase && strings.EqualFold(v, w)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func sameAccountID(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,465 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// exportEntity describes one exported table: its column order and how a row
+// becomes a record. Records hold already-formatted dates so JSON and CSV
+// agree on the contract's YYYY-MM-DD format.
+type exportEntity struct {
+	name   string
+	header []string
+}
+
+var (
+	exportAccounts = exportEntity{"accounts", []string{
+		"id", "account_name", "account_type", "balance", "currency", "is_primary",
+	}}
+	exportTransactions = exportEntity{"transactions", []string{
+		"id", "account_id", "date", "value_date", "title", "amount", "type",
+		"category", "merchant", "tags", "is_transfer", "counterparty", "notes", "external_id", "status",
+	}}
+	exportBudgets = exportEntity{"budgets", []string{
+		"id", "name", "amount", "period", "category", "start_date", "end_date", "is_active",
+	}}
+	exportSavings = exportEntity{"savings_goals", []string{
+		"id", "name", "target_amount", "current_amount", "deadline", "is_completed",
+	}}
+)
+
+// exportSink receives entities one row at a time, so nothing is buffered
+// beyond the row being written.
+type exportSink interface {
+	begin(e exportEntity) error
+	row(e exportEntity, record gin.H) error
+	end(e exportEntity) error
+	close() error
+}
+
+type exportFilter struct {
+	userID    uuid.UUID
+	accountID *uuid.UUID
+	from      *time.Time
+	to        *time.Time
+}
+
+// Export streams the user's data as CSV (a zip with one file per entity),
+// JSON or OFX. from/to limit transactions by date and account_id limits
+// accounts and transactions to one account; budgets and savings goals are
+// always exported in full. OFX only carries accounts and their transactions.
+func (h *Handler) Export(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	filter := exportFilter{userID: userID}
+	if raw := c.Query("account_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+			return
+		}
+		filter.accountID = &id
+	}
+	if raw := c.Query("from"); raw != "" {
+		from, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"})
+			return
+		}
+		filter.from = &from
+	}
+	if raw := c.Query("to"); raw != "" {
+		to, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"})
+			return
+		}
+		filter.to = &to
+	}
+
+	format := c.DefaultQuery("format", "json")
+	stamp := time.Now().Format("20060102")
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="dirav-export-%s.zip"`, stamp))
+		err = h.exportTo(newCSVSink(c.Writer), filter)
+	case "json":
+		c.Header("Content-Type", "application/json")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="dirav-export-%s.json"`, stamp))
+		err = h.exportTo(newJSONSink(c.Writer), filter)
+	case "ofx":
+		c.Header("Content-Type", "application/x-ofx")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="dirav-export-%s.ofx"`, stamp))
+		err = h.exportOFX(c.Writer, filter)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format"})
+		return
+	}
+
+	// Headers and part of the body are already sent, so a failure can only
+	// cut the download short.
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
+
+func (h *Handler) exportTo(sink exportSink, f exportFilter) error {
+	steps := []struct {
+		entity exportEntity
+		query  *gorm.DB
+		write  func(*gorm.DB, exportEntity, exportSink) error
+	}{
+		{exportAccounts, h.exportAccountQuery(f), writeExportRows(accountRecord)},
+		{exportTransactions, h.exportTransactionQuery(f), writeExportRows(transactionRecord)},
+		{exportBudgets, h.DB.Model(&models.Budget{}).Where("user_id = ?", f.userID).Order("created_at"), writeExportRows(budgetRecord)},
+		{exportSavings, h.DB.Model(&models.SavingsGoal{}).Where("user_id = ?", f.userID).Order("created_at"), writeExportRows(savingsRecord)},
+	}
+
+	for _, s := range steps {
+		if err := sink.begin(s.entity); err != nil {
+			return err
+		}
+		if err := s.write(s.query, s.entity, sink); err != nil {
+			return err
+		}
+		if err := sink.end(s.entity); err != nil {
+			return err
+		}
+	}
+	return sink.close()
+}
+
+func (h *Handler) exportAccountQuery(f exportFilter) *gorm.DB {
+	q := h.DB.Model(&models.Account{}).Where("user_id = ?", f.userID)
+	if f.accountID != nil {
+		q = q.Where("id = ?", *f.accountID)
+	}
+	return q.Order("created_at")
+}
+
+func (h *Handler) exportTransactionQuery(f exportFilter) *gorm.DB {
+	q := h.DB.Model(&models.Transaction{}).Where("user_id = ?", f.userID)
+	if f.accountID != nil {
+		q = q.Where("account_id = ?", *f.accountID)
+	}
+	if f.from != nil {
+		q = q.Where("transaction_date >= ?", *f.from)
+	}
+	if f.to != nil {
+		q = q.Where("transaction_date < ?", f.to.AddDate(0, 0, 1))
+	}
+	return q.Order("transaction_date, id")
+}
+
+// writeExportRows walks a query with a database cursor, converting one row
+// at a time.
+func writeExportRows[T any](convert func(T) gin.H) func(*gorm.DB, exportEntity, exportSink) error {
+	return func(q *gorm.DB, e exportEntity, sink exportSink) error {
+		return eachRow(q, func(v T) error {
+			return sink.row(e, convert(v))
+		})
+	}
+}
+
+func eachRow[T any](q *gorm.DB, fn func(T) error) error {
+	rows, err := q.Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v T
+		if err := q.ScanRows(rows, &v); err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func accountRecord(a models.Account) gin.H {
+	return gin.H{
+		"id":           a.ID,
+		"account_name": a.AccountName,
+		"account_type": a.AccountType,
+		"balance":      a.Balance,
+		"currency":     a.Currency,
+		"is_primary":   a.IsPrimary,
+	}
+}
+
+func transactionRecord(t models.Transaction) gin.H {
+	return gin.H{
+		"id":           t.ID,
+		"account_id":   t.AccountID,
+		"date":         exportDate(&t.TransactionDate),
+		"value_date":   exportDate(t.ValueDate),
+		"title":        t.Title,
+		"amount":       t.Amount,
+		"type":         t.Type,
+		"category":     t.Category,
+		"merchant":     t.Merchant,
+		"tags":         strings.Join(t.Tags, ";"),
+		"is_transfer":  t.IsTransfer,
+		"counterparty": t.Counterparty,
+		"notes":        t.Notes,
+		"external_id":  t.ExternalID,
+		"status":       t.Status,
+	}
+}
+
+func budgetRecord(b models.Budget) gin.H {
+	return gin.H{
+		"id":         b.ID,
+		"name":       b.Name,
+		"amount":     b.Amount,
+		"period":     b.Period,
+		"category":   b.Category,
+		"start_date": exportDate(&b.StartDate),
+		"end_date":   exportDate(b.EndDate),
+		"is_active":  b.IsActive,
+	}
+}
+
+func savingsRecord(s models.SavingsGoal) gin.H {
+	return gin.H{
+		"id":             s.ID,
+		"name":           s.Name,
+		"target_amount":  s.TargetAmount,
+		"current_amount": s.CurrentAmount,
+		"deadline":       exportDate(s.Deadline),
+		"is_completed":   s.IsCompleted,
+	}
+}
+
+func exportDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
+
+type csvSink struct {
+	zw *zip.Writer
+	w  *csv.Writer
+}
+
+func newCSVSink(w io.Writer) *csvSink {
+	return &csvSink{zw: zip.NewWriter(w)}
+}
+
+func (s *csvSink) begin(e exportEntity) error {
+	f, err := s.zw.Create(e.name + ".csv")
+	if err != nil {
+		return err
+	}
+	s.w = csv.NewWriter(f)
+	return s.w.Write(e.header)
+}
+
+func (s *csvSink) row(e exportEntity, record gin.H) error {
+	values := make([]string, len(e.header))
+	for i, col := range e.header {
+		values[i] = csvValue(record[col])
+	}
+	return s.w.Write(values)
+}
+
+func (s *csvSink) end(exportEntity) error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+func (s *csvSink) close() error {
+	return s.zw.Close()
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case *uuid.UUID:
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// jsonSink writes {"accounts":[...],"transactions":[...],...}, encoding one
+// element at a time.
+type jsonSink struct {
+	w     io.Writer
+	enc   *json.Encoder
+	first bool
+	count int
+}
+
+func newJSONSink(w io.Writer) *jsonSink {
+	return &jsonSink{w: w, enc: json.NewEncoder(w)}
+}
+
+func (s *jsonSink) begin(e exportEntity) error {
+	sep := ","
+	if s.count == 0 {
+		sep = "{"
+	}
+	s.count++
+	s.first = true
+	_, err := fmt.Fprintf(s.w, "%s%q:[", sep, e.name)
+	return err
+}
+
+func (s *jsonSink) row(_ exportEntity, record gin.H) error {
+	if !s.first {
+		if _, err := io.WriteString(s.w, ","); err != nil {
+			return err
+		}
+	}
+	s.first = false
+	return s.enc.Encode(record)
+}
+
+func (s *jsonSink) end(exportEntity) error {
+	_, err := io.WriteString(s.w, "]")
+	return err
+}
+
+func (s *jsonSink) close() error {
+	if s.count == 0 {
+		_, err := io.WriteString(s.w, "{}")
+		return err
+	}
+	_, err := io.WriteString(s.w, "}\n")
+	return err
+}
+
+// exportOFX writes an OFX 2.2 document with one bank statement per account.
+// Transactions without an account cannot be expressed in OFX and are left
+// out.
+func (h *Handler) exportOFX(w io.Writer, f exportFilter) error {
+	now := time.Now().UTC().Format("20060102150405")
+	if _, err := fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+`, now); err != nil {
+		return err
+	}
+
+	err := eachRow(h.exportAccountQuery(f), func(a models.Account) error {
+		bankID, acctID := ofxAccountIDs(a)
+		currency := a.Currency
+		if currency == "" {
+			currency = "USD"
+		}
+		if _, err := fmt.Fprintf(w, "<STMTTRNRS><TRNUID>%s</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><STMTRS><CURDEF>%s</CURDEF><BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM><BANKTRANLIST>\n",
+			a.ID, ofxText(currency), ofxText(bankID), ofxText(acctID), ofxAccountType(a.AccountType)); err != nil {
+			return err
+		}
+
+		accountFilter := f
+		accountFilter.accountID = &a.ID
+		if err := eachRow(h.exportTransactionQuery(accountFilter), func(t models.Transaction) error {
+			return writeOFXTransaction(w, t)
+		}); err != nil {
+			return err
+		}
+
+		_, err := fmt.Fprintf(w, "</BANKTRANLIST><LEDGERBAL><BALAMT>%.2f</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL></STMTRS></STMTTRNRS>\n", a.Balance, now)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "</BANKMSGSRSV1>\n</OFX>\n")
+	return err
+}
+
+func writeOFXTransaction(w io.Writer, t models.Transaction) error {
+	trnType, amount := "CREDIT", t.Amount
+	if t.Type == "expense" {
+		trnType, amount = "DEBIT", -t.Amount
+	}
+	fitID := t.ExternalID
+	if fitID == "" {
+		fitID = t.ID.String()
+	}
+
+	var memo string
+	if t.Notes != "" {
+		memo = "<MEMO>" + ofxText(t.Notes) + "</MEMO>"
+	}
+	_, err := fmt.Fprintf(w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%.2f</TRNAMT><FITID>%s</FITID><NAME>%s</NAME>%s</STMTTRN>\n",
+		trnType, t.TransactionDate.Format("20060102"), amount, ofxText(fitID), ofxText(truncate(t.Title, 32)), memo)
+	return err
+}
+
+// ofxAccountIDs reuses the bank identifiers learned from imported statements
+// so the export re-imports into the same account elsewhere.
+func ofxAccountIDs(a models.Account) (string, string) {
+	if bank, acct, ok := strings.Cut(a.ExternalAccountID, ":"); ok {
+		return bank, acct
+	}
+	if a.ExternalAccountID != "" {
+		return "", a.ExternalAccountID
+	}
+	return "", a.ID.String()
+}
+
+func ofxAccountType(accountType string) string {
+	switch strings.ToLower(accountType) {
+	case "savings":
+		return "SAVINGS"
+	case "credit", "credit_card":
+		return "CREDITLINE"
+	default:
+		return "CHECKING"
+	}
+}
+
+func ofxText(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func writeSample(t *testing.T, sink exportSink) {
+	t.Helper()
+	date := time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)
+	steps := []struct {
+		entity  exportEntity
+		records []map[string]any
+	}{
+		{exportAccounts, []map[string]any{accountRecord(models.Account{ID: uuid.New(), AccountName: "Wallet", Balance: 12.5})}},
+		{exportTransactions, []map[string]any{
+			transactionRecord(models.Transaction{ID: uuid.New(), Title: "Books, used", Amount: 150, Type: "expense", TransactionDate: date}),
+			transactionRecord(models.Transaction{ID: uuid.New(), Title: "Stipend", Amount: 300, Type: "income", TransactionDate: date}),
+		}},
+		{exportBudgets, nil},
+	}
+	for _, s := range steps {
+		if err := sink.begin(s.entity); err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range s.records {
+			if err := sink.row(s.entity, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sink.end(s.entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	writeSample(t, newJSONSink(&buf))
+
+	var out map[string][]map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(out["accounts"]) != 1 || len(out["transactions"]) != 2 || out["budgets"] == nil {
+		t.Fatalf("unexpected export: %s", buf.String())
+	}
+	if got := out["transactions"][0]["date"]; got != "2025-10-03" {
+		t.Errorf("expected ISO date, got %v", got)
+	}
+}
+
+func TestCSVSink(t *testing.T) {
+	var buf bytes.Buffer
+	writeSample(t, newCSVSink(&buf))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %v", files)
+	}
+	if got := files["budgets.csv"]; got != "id,name,amount,period,category,start_date,end_date,is_active\n" {
+		t.Errorf("unexpected budgets.csv: %q", got)
+	}
+	if !bytes.Contains([]byte(files["transactions.csv"]), []byte(`"Books, used",150.00,expense`)) {
+		t.Errorf("unexpected transactions.csv: %s", files["transactions.csv"])
+	}
+}
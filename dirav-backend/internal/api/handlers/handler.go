@@ -3,6 +3,8 @@ package handlers
 import (
 	"errors"
 
+	"dirav-backend/internal/forecast"
+	"dirav-backend/internal/notify"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,6 +13,17 @@ import (
 type Handler struct {
 	DB        *gorm.DB
 	JWTSecret string
+	// Notifier delivers alerts to users; the standard log when nil.
+	Notifier notify.Notifier
+	// Forecaster projects budget spending; one on the system clock when nil.
+	Forecaster *forecast.Service
+}
+
+func (h *Handler) notifier() notify.Notifier {
+	if h.Notifier == nil {
+		return notify.Log{}
+	}
+	return h.Notifier
 }
 
 func getUserID(c *gin.Context) (uuid.UUID, error) {
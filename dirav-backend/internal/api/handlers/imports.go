@@ -0,0 +1,691 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/importer"
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const maxImportSize = 5 << 20
+
+type importProfileRequest struct {
+	Name string `json:"name"`
+	importer.CSVMapping
+}
+
+var errImportNotFound = errors.New("not found")
+
+func (h *Handler) ListImportProfiles(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var profiles []models.ImportProfile
+	if err := h.DB.Where("user_id = ?", userID).Order("name asc").Find(&profiles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, profiles)
+}
+
+func (h *Handler) CreateImportProfile(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req importProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.Name == "" || req.DateColumn == "" || req.TitleColumn == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+	if req.SkipRows < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": importer.ErrInvalidSkipRows.Error()})
+		return
+	}
+
+	profile := models.ImportProfile{UserID: userID}
+	applyImportProfile(&profile, req)
+
+	if err := h.DB.Create(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusCreated, profile)
+}
+
+func (h *Handler) UpdateImportProfile(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req importProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.Name == "" || req.DateColumn == "" || req.TitleColumn == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+	if req.SkipRows < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": importer.ErrInvalidSkipRows.Error()})
+		return
+	}
+
+	var profile models.ImportProfile
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&profile).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	applyImportProfile(&profile, req)
+
+	if err := h.DB.Save(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) DeleteImportProfile(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.ImportProfile{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// PreviewCSVImport parses an uploaded CSV without writing anything, so the
+// user can check the detected settings and row errors before committing.
+func (h *Handler) PreviewCSVImport(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	data, _, err := readUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mapping, _, err := h.csvMapping(c, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := importer.ParseCSV(data, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid csv", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// CommitCSVImport parses an uploaded CSV and writes its rows into an account
+// as a single import batch. Rows with errors abort the import unless
+// skip_invalid is set.
+func (h *Handler) CommitCSVImport(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.PostForm("account_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid account_id"})
+		return
+	}
+
+	data, fileName, err := readUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	mapping, profileID, err := h.csvMapping(c, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := importer.ParseCSV(data, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid csv", "details": err.Error()})
+		return
+	}
+	if preview.InvalidRows > 0 && c.PostForm("skip_invalid") != "true" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid rows", "invalid_rows": preview.InvalidRows})
+		return
+	}
+
+	var records []importer.Record
+	for _, row := range preview.Rows {
+		if row.Valid() {
+			records = append(records, row.Record)
+		}
+	}
+
+	batch := models.ImportBatch{
+		UserID:    userID,
+		AccountID: accountID,
+		ProfileID: profileID,
+		Source:    "csv",
+		FileName:  fileName,
+	}
+	if err := h.commitImport(&batch, "", records); err != nil {
+		if errors.Is(err, errImportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"batch":        batch,
+		"skipped_rows": preview.InvalidRows,
+	})
+}
+
+// PreviewOFXImport parses an uploaded OFX or QFX statement without writing
+// anything, flagging lines that were already imported.
+func (h *Handler) PreviewOFXImport(c *gin.Context) {
+	h.previewStatement(c, importer.ParseOFX)
+}
+
+// CommitOFXImport imports an OFX or QFX statement into the account given as
+// account_id, or into the account previously linked to the statement's bank
+// account. Lines whose FITID was already imported are skipped.
+func (h *Handler) CommitOFXImport(c *gin.Context) {
+	h.commitStatement(c, "ofx", importer.ParseOFX)
+}
+
+// PreviewCAMTImport parses an uploaded ISO 20022 camt.053 statement without
+// writing anything.
+func (h *Handler) PreviewCAMTImport(c *gin.Context) {
+	h.previewStatement(c, importer.ParseCAMT053)
+}
+
+// CommitCAMTImport imports a camt.053 statement. The statement IBAN links
+// the target account the same way as an OFX account number.
+func (h *Handler) CommitCAMTImport(c *gin.Context) {
+	h.commitStatement(c, "camt053", importer.ParseCAMT053)
+}
+
+func (h *Handler) PreviewMT940Import(c *gin.Context) {
+	h.previewStatement(c, importer.ParseMT940)
+}
+
+func (h *Handler) CommitMT940Import(c *gin.Context) {
+	h.commitStatement(c, "mt940", importer.ParseMT940)
+}
+
+func (h *Handler) PreviewQIFImport(c *gin.Context) {
+	h.previewStatement(c, qifParser(c))
+}
+
+func (h *Handler) CommitQIFImport(c *gin.Context) {
+	h.commitStatement(c, "qif", qifParser(c))
+}
+
+func qifParser(c *gin.Context) func([]byte) (*importer.Statement, error) {
+	dateFormat := c.PostForm("date_format")
+	return func(data []byte) (*importer.Statement, error) {
+		return importer.ParseQIF(data, dateFormat)
+	}
+}
+
+func (h *Handler) ListImports(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var batches []models.ImportBatch
+	if err := h.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&batches).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, batches)
+}
+
+// UndoImport deletes every transaction created by an import batch and
+// reverses its effect on the account balance.
+func (h *Handler) UndoImport(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var batch models.ImportBatch
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ? AND status = ?", id, userID, "committed").
+			First(&batch).Error; err != nil {
+			return errImportNotFound
+		}
+
+		var locked int64
+		if err := tx.Model(&models.Transaction{}).
+			Where("import_batch_id = ? AND status = ?", batch.ID, "reconciled").
+			Count(&locked).Error; err != nil {
+			return err
+		}
+		if locked > 0 {
+			return errTransactionLocked
+		}
+
+		if err := tx.Where("import_batch_id = ? AND user_id = ?", batch.ID, userID).
+			Delete(&models.Transaction{}).Error; err != nil {
+			return err
+		}
+		if err := adjustBalance(tx, batch.AccountID, -batch.NetAmount); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		batch.Status = "undone"
+		batch.UndoneAt = &now
+		return tx.Save(&batch).Error
+	})
+	if err != nil {
+		if errors.Is(err, errImportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if errors.Is(err, errTransactionLocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": "import contains reconciled transactions"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+// commitImport stores records as transactions of batch.AccountID in one
+// database transaction, filling in the batch totals. Records whose external
+// ID is already present in the account are skipped. When accountKey is set
+// and the account is not yet linked to a bank account, it is linked to it.
+func (h *Handler) commitImport(batch *models.ImportBatch, accountKey string, records []importer.Record) error {
+	var txs []models.Transaction
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var account models.Account
+		if err := tx.Where("id = ? AND user_id = ?", batch.AccountID, batch.UserID).First(&account).Error; err != nil {
+			return errImportNotFound
+		}
+
+		seen, err := existingExternalIDs(tx, account.ID, records)
+		if err != nil {
+			return err
+		}
+
+		txs = make([]models.Transaction, 0, len(records))
+		for _, r := range records {
+			if r.ExternalID != "" {
+				if seen[r.ExternalID] {
+					batch.DuplicateCount++
+					continue
+				}
+				seen[r.ExternalID] = true
+			}
+			txs = append(txs, transactionFromRecord(batch.UserID, account.ID, r))
+			batch.NetAmount += r.Amount
+		}
+		batch.RowCount = len(txs)
+
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+		for i := range txs {
+			txs[i].ImportBatchID = &batch.ID
+		}
+		if err := h.createTransactions(tx, txs); err != nil {
+			return err
+		}
+
+		if accountKey != "" && account.ExternalAccountID == "" {
+			if err := tx.Model(&account).Update("external_account_id", accountKey).Error; err != nil {
+				return err
+			}
+		}
+		return adjustBalance(tx, account.ID, batch.NetAmount)
+	})
+	if err == nil {
+		h.checkBudgetAlerts(batch.UserID, txs)
+	}
+	return err
+}
+
+// existingExternalIDs returns which of the records' external IDs are already
+// stored on transactions of the account.
+func existingExternalIDs(db *gorm.DB, accountID uuid.UUID, records []importer.Record) (map[string]bool, error) {
+	var ids []string
+	for _, r := range records {
+		if r.ExternalID != "" {
+			ids = append(ids, r.ExternalID)
+		}
+	}
+
+	seen := map[string]bool{}
+	if len(ids) == 0 {
+		return seen, nil
+	}
+
+	var existing []string
+	if err := db.Model(&models.Transaction{}).
+		Where("account_id = ? AND external_id IN ?", accountID, ids).
+		Pluck("external_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range existing {
+		seen[id] = true
+	}
+	return seen, nil
+}
+
+// markDuplicates flags preview rows that committing into the account would
+// skip as already imported.
+func markDuplicates(db *gorm.DB, accountID uuid.UUID, rows []importer.Row) error {
+	records := make([]importer.Record, len(rows))
+	for i, row := range rows {
+		records[i] = row.Record
+	}
+	seen, err := existingExternalIDs(db, accountID, records)
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		id := rows[i].Record.ExternalID
+		if id == "" {
+			continue
+		}
+		rows[i].Duplicate = seen[id]
+		seen[id] = true
+	}
+	return nil
+}
+
+// statementAccount picks the account a statement is imported into: the
+// account_id form field when given, otherwise the account already linked to
+// the bank account named in the statement. It returns uuid.Nil when neither
+// identifies an account of the user.
+func (h *Handler) statementAccount(c *gin.Context, userID uuid.UUID, stmt *importer.Statement) (uuid.UUID, error) {
+	if raw := c.PostForm("account_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return uuid.Nil, errors.New("invalid account_id")
+		}
+		return id, nil
+	}
+
+	key := stmt.Account.Key()
+	if key == "" {
+		return uuid.Nil, nil
+	}
+	var account models.Account
+	err := h.DB.Where("user_id = ? AND external_account_id = ?", userID, key).First(&account).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, nil
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return account.ID, nil
+}
+
+func (h *Handler) previewStatement(c *gin.Context, parse func([]byte) (*importer.Statement, error)) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	data, _, err := readUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stmt, err := parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid statement", "details": err.Error()})
+		return
+	}
+
+	accountID, err := h.statementAccount(c, userID, stmt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var matched *uuid.UUID
+	if accountID != uuid.Nil {
+		matched = &accountID
+		if err := markDuplicates(h.DB.Where("user_id = ?", userID), accountID, stmt.Rows); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"account_id": matched,
+		"statement":  stmt,
+	})
+}
+
+func (h *Handler) commitStatement(c *gin.Context, source string, parse func([]byte) (*importer.Statement, error)) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	data, fileName, err := readUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stmt, err := parse(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid statement", "details": err.Error()})
+		return
+	}
+	if stmt.InvalidRows > 0 && c.PostForm("skip_invalid") != "true" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "invalid rows", "invalid_rows": stmt.InvalidRows})
+		return
+	}
+
+	accountID, err := h.statementAccount(c, userID, stmt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if accountID == uuid.Nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "no matching account", "statement_account": stmt.Account})
+		return
+	}
+
+	var records []importer.Record
+	for _, row := range stmt.Rows {
+		if row.Valid() {
+			records = append(records, row.Record)
+		}
+	}
+
+	batch := models.ImportBatch{
+		UserID:    userID,
+		AccountID: accountID,
+		Source:    source,
+		FileName:  fileName,
+	}
+	if err := h.commitImport(&batch, stmt.Account.Key(), records); err != nil {
+		if errors.Is(err, errImportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"batch":        batch,
+		"skipped_rows": stmt.InvalidRows,
+	})
+}
+
+func (h *Handler) csvMapping(c *gin.Context, userID uuid.UUID) (importer.CSVMapping, *uuid.UUID, error) {
+	if raw := c.PostForm("profile_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return importer.CSVMapping{}, nil, errors.New("invalid profile_id")
+		}
+		var profile models.ImportProfile
+		if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&profile).Error; err != nil {
+			return importer.CSVMapping{}, nil, errors.New("profile not found")
+		}
+		return profileMapping(profile), &profile.ID, nil
+	}
+
+	var mapping importer.CSVMapping
+	if err := json.Unmarshal([]byte(c.PostForm("mapping")), &mapping); err != nil {
+		return importer.CSVMapping{}, nil, errors.New("invalid mapping")
+	}
+	if mapping.SkipRows < 0 {
+		return importer.CSVMapping{}, nil, importer.ErrInvalidSkipRows
+	}
+	return mapping, nil, nil
+}
+
+func readUpload(c *gin.Context) ([]byte, string, error) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, "", errors.New("missing file")
+	}
+	if header.Size > maxImportSize {
+		return nil, "", fmt.Errorf("file exceeds %d bytes", maxImportSize)
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		return nil, "", errors.New("unreadable file")
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxImportSize))
+	if err != nil {
+		return nil, "", errors.New("unreadable file")
+	}
+	return data, header.Filename, nil
+}
+
+// transactionFromRecord converts a statement line. Lines come from the bank,
+// so they are already cleared.
+func transactionFromRecord(userID, accountID uuid.UUID, r importer.Record) models.Transaction {
+	txType := "income"
+	amount := r.Amount
+	if amount < 0 {
+		txType = "expense"
+		amount = -amount
+	}
+	return models.Transaction{
+		UserID:          userID,
+		AccountID:       &accountID,
+		Title:           r.Title,
+		Amount:          amount,
+		Type:            txType,
+		Category:        r.Category,
+		TransactionDate: r.Date,
+		ValueDate:       r.ValueDate,
+		Counterparty:    r.Counterparty,
+		Notes:           r.Remittance,
+		ExternalID:      r.ExternalID,
+		Status:          "cleared",
+	}
+}
+
+func profileMapping(p models.ImportProfile) importer.CSVMapping {
+	return importer.CSVMapping{
+		Delimiter:      p.Delimiter,
+		Encoding:       p.Encoding,
+		DateFormat:     p.DateFormat,
+		HasHeader:      p.HasHeader,
+		SkipRows:       p.SkipRows,
+		DateColumn:     p.DateColumn,
+		TitleColumn:    p.TitleColumn,
+		AmountColumn:   p.AmountColumn,
+		DebitColumn:    p.DebitColumn,
+		CreditColumn:   p.CreditColumn,
+		CategoryColumn: p.CategoryColumn,
+		InvertSign:     p.InvertSign,
+	}
+}
+
+func applyImportProfile(p *models.ImportProfile, req importProfileRequest) {
+	p.Name = req.Name
+	p.Delimiter = req.Delimiter
+	p.Encoding = req.Encoding
+	p.DateFormat = req.DateFormat
+	p.HasHeader = req.HasHeader
+	p.SkipRows = req.SkipRows
+	p.DateColumn = req.DateColumn
+	p.TitleColumn = req.TitleColumn
+	p.AmountColumn = req.AmountColumn
+	p.DebitColumn = req.DebitColumn
+	p.CreditColumn = req.CreditColumn
+	p.CategoryColumn = req.CategoryColumn
+	p.InvertSign = req.InvertSign
+}
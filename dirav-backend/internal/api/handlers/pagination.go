@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+
+	// nextCursorHeader carries the cursor of the next page. List bodies stay
+	// plain arrays, as the frontend contract defines them, unless the
+	// client asks for a page envelope with envelope=true.
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// sortField maps a public sort name onto a column and the struct field that
+// holds its value. Only non-null columns are sortable, so keyset comparisons
+// never meet NULL.
+type sortField struct {
+	column string
+	field  string
+	kind   string // "time", "number" or "text"
+}
+
+// listSpec describes how a list endpoint can be sorted.
+type listSpec struct {
+	sorts       map[string]sortField
+	defaultSort string
+}
+
+// page is a parsed page request: a sort key with direction, a size, an
+// optional position after which the page starts and whether the body is
+// wrapped in a pageEnvelope.
+type page struct {
+	sort     string
+	field    sortField
+	desc     bool
+	limit    int
+	after    *pageCursor
+	envelope bool
+}
+
+// pageEnvelope is a list body that carries the next page's cursor itself,
+// nil on the last page.
+type pageEnvelope struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"next_cursor"`
+}
+
+// pageCursor is the position of the last row of a page. It is handed to
+// clients base64-encoded and is opaque to them.
+type pageCursor struct {
+	Sort  string    `json:"s"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// parsePage reads limit, sort and cursor from the query string. Sizes above
+// maxPageSize are capped.
+func parsePage(c *gin.Context, spec listSpec) (page, error) {
+	p := page{limit: defaultPageSize, sort: spec.defaultSort}
+
+	switch c.Query("envelope") {
+	case "", "false":
+	case "true":
+		p.envelope = true
+	default:
+		return p, errors.New("invalid envelope")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 1 {
+			return p, errors.New("invalid limit")
+		}
+		p.limit = v
+	}
+	if p.limit > maxPageSize {
+		p.limit = maxPageSize
+	}
+
+	if raw := c.Query("sort"); raw != "" {
+		p.sort = raw
+	}
+	name := strings.TrimPrefix(p.sort, "-")
+	field, ok := spec.sorts[name]
+	if !ok {
+		return p, fmt.Errorf("invalid sort %q", p.sort)
+	}
+	p.field = field
+	p.desc = strings.HasPrefix(p.sort, "-")
+
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := decodeCursor(raw)
+		if err != nil || cur.Sort != p.sort {
+			return p, errInvalidCursor
+		}
+		if _, err := cursorValue(field, cur.Value); err != nil {
+			return p, errInvalidCursor
+		}
+		p.after = &cur
+	}
+	return p, nil
+}
+
+// apply orders the query by the sort key with id as tie-breaker, starts it
+// after the cursor and fetches one row more than the page holds so that
+// finishPage can tell whether another page follows.
+func (p page) apply(query *gorm.DB) *gorm.DB {
+	dir, cmp := "ASC", ">"
+	if p.desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if p.after != nil {
+		value, _ := cursorValue(p.field, p.after.Value)
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", p.field.column, cmp),
+			value, value, p.after.ID,
+		)
+	}
+	return query.
+		Order(fmt.Sprintf("%s %s, id %s", p.field.column, dir, dir)).
+		Limit(p.limit + 1)
+}
+
+// finishPage trims the extra row fetched by apply and, when there is one,
+// sets the cursor header for the next page. It returns the page's rows and
+// the next cursor, empty on the last page.
+func finishPage[T any](c *gin.Context, p page, rows []T) ([]T, string) {
+	if rows == nil {
+		rows = []T{}
+	}
+	if len(rows) <= p.limit {
+		return rows, ""
+	}
+	rows = rows[:p.limit]
+
+	last := reflect.ValueOf(rows[len(rows)-1])
+	cur := pageCursor{
+		Sort:  p.sort,
+		Value: formatCursorValue(last.FieldByName(p.field.field).Interface()),
+		ID:    last.FieldByName("ID").Interface().(uuid.UUID),
+	}
+	next := encodeCursor(cur)
+	c.Header(nextCursorHeader, next)
+	return rows, next
+}
+
+// writePage writes a page of list items, as a plain array or, when the
+// client asked for one, in a pageEnvelope with the next cursor.
+func writePage(c *gin.Context, p page, items interface{}, next string) {
+	if !p.envelope {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+	body := pageEnvelope{Data: items}
+	if next != "" {
+		body.NextCursor = &next
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// listPage runs a paginated list query and writes the page, or the error.
+func listPage[T any](c *gin.Context, query *gorm.DB, spec listSpec) {
+	p, err := parsePage(c, spec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rows []T
+	if err := p.apply(query).Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	rows, next := finishPage(c, p, rows)
+	writePage(c, p, rows, next)
+}
+
+func encodeCursor(cur pageCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var cur pageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(raw, &cur)
+	return cur, err
+}
+
+func formatCursorValue(v interface{}) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func cursorValue(f sortField, s string) (interface{}, error) {
+	switch f.kind {
+	case "time":
+		return time.Parse(time.RFC3339Nano, s)
+	case "number":
+		return strconv.ParseFloat(s, 64)
+	default:
+		return s, nil
+	}
+}
+
+// queryDate parses an optional YYYY-MM-DD query parameter.
+func queryDate(c *gin.Context, name string) (*time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &t, nil
+}
+
+// queryFloat parses an optional numeric query parameter.
+func queryFloat(c *gin.Context, name string) (*float64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &v, nil
+}
+
+// queryUUID parses an optional UUID query parameter.
+func queryUUID(c *gin.Context, name string) (*uuid.UUID, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s", name)
+	}
+	return &id, nil
+}
+
+// likePattern turns free text into an ILIKE pattern matching it anywhere,
+// with LIKE wildcards in the text taken literally.
+func likePattern(q string) string {
+	return "%" + escapeLike(strings.TrimSpace(q)) + "%"
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
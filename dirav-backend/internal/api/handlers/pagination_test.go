@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func pageContext(query string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/transactions?"+query, nil)
+	return c, w
+}
+
+func TestParsePage(t *testing.T) {
+	c, _ := pageContext("")
+	p, err := parsePage(c, transactionList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.limit != defaultPageSize || p.field.column != "transaction_date" || !p.desc {
+		t.Errorf("defaults = %+v", p)
+	}
+
+	c, _ = pageContext("limit=100000&sort=amount")
+	p, err = parsePage(c, transactionList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.limit != maxPageSize || p.field.column != "amount" || p.desc {
+		t.Errorf("got %+v, want capped ascending amount", p)
+	}
+
+	for _, q := range []string{"limit=0", "limit=abc", "sort=balance", "cursor=bm90IGpzb24", "envelope=yes", "sort=amount&cursor=" + encodeCursor(pageCursor{Sort: "-date"})} {
+		c, _ = pageContext(q)
+		if _, err := parsePage(c, transactionList); err == nil {
+			t.Errorf("%s: expected an error", q)
+		}
+	}
+}
+
+func TestFinishPageCursor(t *testing.T) {
+	date := time.Date(2025, 10, 3, 12, 30, 0, 123456000, time.UTC)
+	rows := []models.Transaction{
+		{ID: uuid.New(), TransactionDate: date.AddDate(0, 0, 1)},
+		{ID: uuid.New(), TransactionDate: date},
+		{ID: uuid.New(), TransactionDate: date.AddDate(0, 0, -1)},
+	}
+
+	c, w := pageContext("limit=2")
+	p, err := parsePage(c, transactionList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, next := finishPage(c, p, rows)
+	if len(got) != 2 {
+		t.Fatalf("got %d rows, want 2", len(got))
+	}
+	if h := w.Header().Get(nextCursorHeader); h != next {
+		t.Errorf("header = %q, want the returned cursor %q", h, next)
+	}
+
+	c, _ = pageContext("limit=2&cursor=" + next)
+	p, err = parsePage(c, transactionList)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := cursorValue(p.field, p.after.Value)
+	if p.after.ID != rows[1].ID || !value.(time.Time).Equal(date) {
+		t.Errorf("cursor = %+v, want position of second row", p.after)
+	}
+
+	c, w = pageContext("")
+	if _, next := finishPage(c, p, rows[:1]); next != "" || w.Header().Get(nextCursorHeader) != "" {
+		t.Error("last page must not carry a cursor")
+	}
+}
+
+func TestWritePageEnvelope(t *testing.T) {
+	c, w := pageContext("")
+	p, _ := parsePage(c, transactionList)
+	writePage(c, p, []int{1, 2}, "abc")
+	if got := w.Body.String(); got != "[1,2]" {
+		t.Errorf("plain body = %s, want [1,2]", got)
+	}
+
+	c, w = pageContext("envelope=true")
+	p, _ = parsePage(c, transactionList)
+	writePage(c, p, []int{1, 2}, "abc")
+	var body struct {
+		Data       []int   `json:"data"`
+		NextCursor *string `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 2 || body.NextCursor == nil || *body.NextCursor != "abc" {
+		t.Errorf("envelope = %s", w.Body.String())
+	}
+
+	c, w = pageContext("envelope=true")
+	writePage(c, p, []int{}, "")
+	if got := w.Body.String(); got != `{"data":[],"next_cursor":null}` {
+		t.Errorf("last page = %s", got)
+	}
+}
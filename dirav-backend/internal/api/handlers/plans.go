@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/planning"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errPlanNotFound   = errors.New("not found")
+	errPlanExists     = errors.New("plan already exists")
+	errNoPreviousPlan = errors.New("no plan for the previous month")
+)
+
+type planRequest struct {
+	// ExpectedIncome left out makes the plan use the month's income
+	// transactions.
+	ExpectedIncome *float64                `json:"expected_income"`
+	Allocations    []planAllocationRequest `json:"allocations"`
+}
+
+type planAllocationRequest struct {
+	BudgetID      *uuid.UUID `json:"budget_id"`
+	SavingsGoalID *uuid.UUID `json:"savings_goal_id"`
+	Amount        float64    `json:"amount"`
+}
+
+// planView is a plan with its allocations and where it stands.
+type planView struct {
+	ID             uuid.UUID            `json:"id"`
+	Month          string               `json:"month"`
+	ExpectedIncome *float64             `json:"expected_income"`
+	ActualIncome   float64              `json:"actual_income"`
+	IncomeSource   string               `json:"income_source"`
+	Allocations    []planAllocationView `json:"allocations"`
+	planning.Summary
+}
+
+type planAllocationView struct {
+	ID            uuid.UUID  `json:"id"`
+	BudgetID      *uuid.UUID `json:"budget_id,omitempty"`
+	SavingsGoalID *uuid.UUID `json:"savings_goal_id,omitempty"`
+	Name          string     `json:"name"`
+	Amount        float64    `json:"amount"`
+}
+
+// GetPlan returns the plan for a YYYY-MM month.
+func (h *Handler) GetPlan(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	month, err := planning.ParseMonth(c.Param("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var plan models.MonthlyPlan
+	if err := h.DB.Where("user_id = ? AND month = ?", userID, month).First(&plan).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	view, err := buildPlanView(h.DB, plan)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, view)
+}
+
+// SavePlan creates or replaces the plan for a month. The allocations sent
+// replace all earlier ones.
+func (h *Handler) SavePlan(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	month, err := planning.ParseMonth(c.Param("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req planRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.ExpectedIncome != nil && *req.ExpectedIncome < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected_income must not be negative"})
+		return
+	}
+
+	allocations, err := planAllocations(userID, req.Allocations)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var view planView
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkPlanTargets(tx, userID, allocations); err != nil {
+			return err
+		}
+
+		plan := models.MonthlyPlan{UserID: userID, Month: month}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&plan).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND month = ?", userID, month).
+			First(&plan).Error; err != nil {
+			return err
+		}
+
+		plan.ExpectedIncome = req.ExpectedIncome
+		if err := tx.Model(&plan).Update("expected_income", plan.ExpectedIncome).Error; err != nil {
+			return err
+		}
+		if err := replacePlanAllocations(tx, plan.ID, allocations); err != nil {
+			return err
+		}
+
+		var err error
+		view, err = buildPlanView(tx, plan)
+		return err
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, view)
+	case errors.Is(err, errPlanNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "budget or savings goal not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	}
+}
+
+// CopyPlan starts a month's plan from the previous month's: the same
+// expected income and allocations. Allocations to budgets that are no
+// longer active and savings goals that are completed or gone are dropped.
+func (h *Handler) CopyPlan(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	month, err := planning.ParseMonth(c.Param("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var view planView
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var previous models.MonthlyPlan
+		if err := tx.Where("user_id = ? AND month = ?", userID, planning.Previous(month)).
+			First(&previous).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errNoPreviousPlan
+			}
+			return err
+		}
+
+		plan := models.MonthlyPlan{UserID: userID, Month: month, ExpectedIncome: previous.ExpectedIncome}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&plan)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errPlanExists
+		}
+
+		var allocations []models.PlanAllocation
+		if err := tx.Where("plan_id = ?", previous.ID).
+			Where("(budget_id IS NULL OR budget_id IN (?))",
+				tx.Model(&models.Budget{}).Select("id").Where("user_id = ? AND is_active = true", userID)).
+			Where("(savings_goal_id IS NULL OR savings_goal_id IN (?))",
+				tx.Model(&models.SavingsGoal{}).Select("id").Where("user_id = ? AND is_completed = false", userID)).
+			Find(&allocations).Error; err != nil {
+			return err
+		}
+		for i := range allocations {
+			allocations[i].ID = uuid.Nil
+			allocations[i].CreatedAt = time.Time{}
+		}
+		if err := replacePlanAllocations(tx, plan.ID, allocations); err != nil {
+			return err
+		}
+
+		var err error
+		view, err = buildPlanView(tx, plan)
+		return err
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusCreated, view)
+	case errors.Is(err, errNoPreviousPlan):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errPlanExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	}
+}
+
+// DeletePlan removes a month's plan and its allocations.
+func (h *Handler) DeletePlan(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	month, err := planning.ParseMonth(c.Param("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var plan models.MonthlyPlan
+		if err := tx.Where("user_id = ? AND month = ?", userID, month).First(&plan).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := tx.Where("plan_id = ?", plan.ID).Delete(&models.PlanAllocation{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&plan).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// planAllocations validates allocation requests: each names exactly one
+// budget or savings goal, at most once, with an amount that is not
+// negative.
+func planAllocations(userID uuid.UUID, reqs []planAllocationRequest) ([]models.PlanAllocation, error) {
+	seen := map[uuid.UUID]bool{}
+	out := make([]models.PlanAllocation, 0, len(reqs))
+	for _, r := range reqs {
+		if (r.BudgetID == nil) == (r.SavingsGoalID == nil) {
+			return nil, errors.New("each allocation needs either budget_id or savings_goal_id")
+		}
+		if r.Amount < 0 {
+			return nil, errors.New("allocation amounts must not be negative")
+		}
+		target := r.BudgetID
+		if target == nil {
+			target = r.SavingsGoalID
+		}
+		if seen[*target] {
+			return nil, errors.New("duplicate allocation")
+		}
+		seen[*target] = true
+
+		out = append(out, models.PlanAllocation{
+			UserID:        userID,
+			BudgetID:      r.BudgetID,
+			SavingsGoalID: r.SavingsGoalID,
+			Amount:        roundCents(r.Amount),
+		})
+	}
+	return out, nil
+}
+
+// checkPlanTargets makes sure every budget and savings goal allocated to
+// belongs to the user.
+func checkPlanTargets(db *gorm.DB, userID uuid.UUID, allocations []models.PlanAllocation) error {
+	var budgetIDs, goalIDs []uuid.UUID
+	for _, a := range allocations {
+		if a.BudgetID != nil {
+			budgetIDs = append(budgetIDs, *a.BudgetID)
+		} else {
+			goalIDs = append(goalIDs, *a.SavingsGoalID)
+		}
+	}
+
+	for _, check := range []struct {
+		model interface{}
+		ids   []uuid.UUID
+	}{{&models.Budget{}, budgetIDs}, {&models.SavingsGoal{}, goalIDs}} {
+		if len(check.ids) == 0 {
+			continue
+		}
+		var count int64
+		if err := db.Model(check.model).
+			Where("id IN ? AND user_id = ?", check.ids, userID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(check.ids) {
+			return errPlanNotFound
+		}
+	}
+	return nil
+}
+
+func replacePlanAllocations(db *gorm.DB, planID uuid.UUID, allocations []models.PlanAllocation) error {
+	if err := db.Where("plan_id = ?", planID).Delete(&models.PlanAllocation{}).Error; err != nil {
+		return err
+	}
+	if len(allocations) == 0 {
+		return nil
+	}
+	for i := range allocations {
+		allocations[i].PlanID = planID
+	}
+	return db.Create(&allocations).Error
+}
+
+// buildPlanView loads a plan's allocations with the names of what they are
+// for, and the month's income.
+func buildPlanView(db *gorm.DB, plan models.MonthlyPlan) (planView, error) {
+	start, end := planning.MonthRange(plan.Month)
+	view := planView{
+		ID:             plan.ID,
+		Month:          start.Format(planning.MonthLayout),
+		ExpectedIncome: plan.ExpectedIncome,
+		Allocations:    []planAllocationView{},
+	}
+
+	if err := db.Model(&models.Transaction{}).
+		Where("user_id = ? AND type = ? AND is_transfer = false", plan.UserID, "income").
+		Where("transaction_date >= ? AND transaction_date < ?", start, end).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&view.ActualIncome).Error; err != nil {
+		return view, err
+	}
+	view.ActualIncome = roundCents(view.ActualIncome)
+
+	var rows []struct {
+		models.PlanAllocation
+		Name string
+	}
+	if err := db.Table("plan_allocations AS a").
+		Select("a.*, COALESCE(b.name, g.name, '') AS name").
+		Joins("LEFT JOIN budgets b ON b.id = a.budget_id").
+		Joins("LEFT JOIN savings_goals g ON g.id = a.savings_goal_id").
+		Where("a.plan_id = ?", plan.ID).
+		Order("a.savings_goal_id IS NOT NULL, name").
+		Scan(&rows).Error; err != nil {
+		return view, err
+	}
+
+	amounts := make([]float64, 0, len(rows))
+	for _, r := range rows {
+		view.Allocations = append(view.Allocations, planAllocationView{
+			ID:            r.ID,
+			BudgetID:      r.BudgetID,
+			SavingsGoalID: r.SavingsGoalID,
+			Name:          r.Name,
+			Amount:        r.Amount,
+		})
+		amounts = append(amounts, r.Amount)
+	}
+
+	income, source := view.ActualIncome, "transactions"
+	if plan.ExpectedIncome != nil {
+		income, source = *plan.ExpectedIncome, "expected"
+	}
+	view.IncomeSource = source
+	view.Summary = planning.Summarize(income, amounts)
+	return view, nil
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPlanAllocations(t *testing.T) {
+	userID, budget, goal := uuid.New(), uuid.New(), uuid.New()
+
+	got, err := planAllocations(userID, []planAllocationRequest{
+		{BudgetID: &budget, Amount: 250.555},
+		{SavingsGoalID: &goal, Amount: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Amount != 250.56 || got[0].UserID != userID || *got[1].SavingsGoalID != goal {
+		t.Errorf("allocations = %+v", got)
+	}
+
+	bad := map[string][]planAllocationRequest{
+		"no target":       {{Amount: 10}},
+		"two targets":     {{BudgetID: &budget, SavingsGoalID: &goal, Amount: 10}},
+		"negative amount": {{BudgetID: &budget, Amount: -1}},
+		"duplicate":       {{BudgetID: &budget, Amount: 10}, {BudgetID: &budget, Amount: 20}},
+	}
+	for name, reqs := range bad {
+		if _, err := planAllocations(userID, reqs); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errReconciliationNotFound   = errors.New("not found")
+	errReconciliationUnbalanced = errors.New("cleared balance does not match the statement")
+	errTransactionLocked        = errors.New("transaction is reconciled")
+)
+
+type reconciliationRequest struct {
+	StatementDate    string   `json:"statement_date"`
+	StatementBalance *float64 `json:"statement_balance"`
+	OpeningBalance   *float64 `json:"opening_balance"`
+}
+
+type clearTransactionsRequest struct {
+	TransactionIDs []uuid.UUID `json:"transaction_ids"`
+	Cleared        bool        `json:"cleared"`
+}
+
+// reconciliationSummary is a session with its running totals and the
+// account's transactions still open for ticking.
+type reconciliationSummary struct {
+	models.Reconciliation
+	ClearedTotal   float64              `json:"cleared_total"`
+	ClearedBalance float64              `json:"cleared_balance"`
+	Difference     float64              `json:"difference"`
+	AccountBalance float64              `json:"account_balance"`
+	Transactions   []models.Transaction `json:"transactions,omitempty"`
+}
+
+func (h *Handler) ListReconciliations(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var list []models.Reconciliation
+	if err := h.DB.Where("user_id = ? AND account_id = ?", userID, accountID).
+		Order("statement_date desc, created_at desc").
+		Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// StartReconciliation opens a session for an account. The opening balance
+// defaults to the ending balance of the last completed session.
+func (h *Handler) StartReconciliation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req reconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.StatementDate == "" || req.StatementBalance == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+	date, err := time.Parse("2006-01-02", req.StatementDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+		return
+	}
+
+	var account models.Account
+	if err := h.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	var open int64
+	if err := h.DB.Model(&models.Reconciliation{}).
+		Where("account_id = ? AND status = ?", account.ID, "open").
+		Count(&open).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if open > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "account already has an open reconciliation"})
+		return
+	}
+
+	rec := models.Reconciliation{
+		UserID:           userID,
+		AccountID:        account.ID,
+		StatementDate:    date,
+		StatementBalance: *req.StatementBalance,
+	}
+
+	var last models.Reconciliation
+	err = h.DB.Where("account_id = ? AND status = ?", account.ID, "completed").
+		Order("statement_date desc").
+		First(&last).Error
+	switch {
+	case err == nil:
+		if !date.After(last.StatementDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "statement_date must be after the last reconciled statement"})
+			return
+		}
+		rec.OpeningBalance = last.StatementBalance
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if req.OpeningBalance != nil {
+		rec.OpeningBalance = *req.OpeningBalance
+	}
+
+	if err := h.DB.Create(&rec).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	summary, err := reconciliationStatus(h.DB, rec, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusCreated, summary)
+}
+
+// GetReconciliation returns a session with its totals. Open sessions also
+// list the account's unreconciled transactions up to the statement date.
+func (h *Handler) GetReconciliation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var rec models.Reconciliation
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&rec).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	summary, err := reconciliationStatus(h.DB, rec, rec.Status == "open")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// ClearTransactions ticks (or unticks) transactions in an open session,
+// moving them between pending and cleared.
+func (h *Handler) ClearTransactions(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req clearTransactionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if len(req.TransactionIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+
+	var rec models.Reconciliation
+	if err := h.DB.Where("id = ? AND user_id = ? AND status = ?", id, userID, "open").First(&rec).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	status := "pending"
+	if req.Cleared {
+		status = "cleared"
+	}
+	if err := h.DB.Model(&models.Transaction{}).
+		Where("id IN ? AND user_id = ? AND account_id = ? AND status <> ? AND reconciliation_id IS NULL",
+			req.TransactionIDs, userID, rec.AccountID, "reconciled").
+		Update("status", status).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	summary, err := reconciliationStatus(h.DB, rec, false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// FinishReconciliation completes a session whose cleared balance matches the
+// statement, locking every cleared transaction up to the statement date.
+// Transactions unlocked from earlier sessions are locked again there.
+func (h *Handler) FinishReconciliation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var summary reconciliationSummary
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var rec models.Reconciliation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ? AND status = ?", id, userID, "open").
+			First(&rec).Error; err != nil {
+			return errReconciliationNotFound
+		}
+
+		var err error
+		summary, err = reconciliationStatus(tx, rec, false)
+		if err != nil {
+			return err
+		}
+		if summary.Difference != 0 {
+			return errReconciliationUnbalanced
+		}
+
+		res := tx.Model(&models.Transaction{}).
+			Where("account_id = ? AND status = ? AND reconciliation_id IS NULL AND transaction_date < ?",
+				rec.AccountID, "cleared", statementEnd(rec)).
+			Updates(map[string]interface{}{"status": "reconciled", "reconciliation_id": rec.ID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if err := tx.Model(&models.Transaction{}).
+			Where("account_id = ? AND status <> ? AND reconciliation_id IS NOT NULL", rec.AccountID, "reconciled").
+			Update("status", "reconciled").Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		rec.Status = "completed"
+		rec.TransactionCount = int(res.RowsAffected)
+		rec.CompletedAt = &now
+		if err := tx.Save(&rec).Error; err != nil {
+			return err
+		}
+		summary.Reconciliation = rec
+		return nil
+	})
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, summary)
+	case errors.Is(err, errReconciliationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, errReconciliationUnbalanced):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "difference": summary.Difference})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	}
+}
+
+// CancelReconciliation abandons an open session. Ticked transactions stay
+// cleared.
+func (h *Handler) CancelReconciliation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	res := h.DB.Model(&models.Reconciliation{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, "open").
+		Update("status", "cancelled")
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "cancelled"})
+}
+
+// UnlockTransaction lets a reconciled transaction be edited again. It goes
+// back to cleared but stays with its reconciliation, whose statement balance
+// already counts it, so later sessions neither count nor clear it again.
+// Finishing the account's next session locks it again.
+func (h *Handler) UnlockTransaction(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	res := h.DB.Model(&models.Transaction{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, "reconciled").
+		Update("status", "cleared")
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "unlocked"})
+}
+
+// reconciliationStatus computes a session's totals: the cleared balance is
+// the opening balance plus every cleared transaction up to the statement
+// date that no session has reconciled yet.
+func reconciliationStatus(db *gorm.DB, rec models.Reconciliation, withTransactions bool) (reconciliationSummary, error) {
+	summary := reconciliationSummary{Reconciliation: rec}
+
+	var total struct{ Total float64 }
+	if err := db.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN type = 'expense' THEN -amount ELSE amount END), 0) AS total").
+		Where("account_id = ? AND status = ? AND reconciliation_id IS NULL AND transaction_date < ?",
+			rec.AccountID, "cleared", statementEnd(rec)).
+		Scan(&total).Error; err != nil {
+		return summary, err
+	}
+
+	var account models.Account
+	if err := db.Select("balance").Where("id = ?", rec.AccountID).First(&account).Error; err != nil {
+		return summary, err
+	}
+
+	summary.ClearedTotal = roundCents(total.Total)
+	summary.AccountBalance = account.Balance
+	if rec.Status == "completed" {
+		summary.ClearedBalance = rec.StatementBalance
+	} else {
+		summary.ClearedBalance = roundCents(rec.OpeningBalance + total.Total)
+		summary.Difference = roundCents(rec.StatementBalance - summary.ClearedBalance)
+	}
+
+	if withTransactions {
+		if err := db.Where("account_id = ? AND status <> ? AND reconciliation_id IS NULL AND transaction_date < ?",
+			rec.AccountID, "reconciled", statementEnd(rec)).
+			Order("transaction_date, created_at").
+			Find(&summary.Transactions).Error; err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// transactionLocked reports whether a transaction of the user is reconciled.
+// Missing transactions are not locked.
+func transactionLocked(db *gorm.DB, userID, id uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Model(&models.Transaction{}).
+		Where("id = ? AND user_id = ? AND status = ?", id, userID, "reconciled").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// statementEnd is the first instant after the statement date.
+func statementEnd(rec models.Reconciliation) time.Time {
+	return rec.StatementDate.AddDate(0, 0, 1)
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
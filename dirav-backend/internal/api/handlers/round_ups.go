@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/savings"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type roundUpSettings struct {
+	// GoalID turns round-ups on for a goal; null turns them off.
+	GoalID    *uuid.UUID `json:"goal_id"`
+	AccountID *uuid.UUID `json:"account_id"`
+}
+
+type roundUpReport struct {
+	From        string         `json:"from"`
+	To          string         `json:"to"`
+	Count       int            `json:"count"`
+	Total       float64        `json:"total"`
+	Contributed float64        `json:"contributed"`
+	Pending     float64        `json:"pending"`
+	Months      []roundUpMonth `json:"months"`
+}
+
+type roundUpMonth struct {
+	Month   string  `json:"month"`
+	Count   int     `json:"count"`
+	Total   float64 `json:"total"`
+	Pending float64 `json:"pending"`
+}
+
+// GetRoundUpSettings returns which goal, if any, expenses are rounded up
+// into.
+func (h *Handler) GetRoundUpSettings(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Select("round_up_goal_id", "round_up_account_id").First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, roundUpSettings{GoalID: user.RoundUpGoalID, AccountID: user.RoundUpAccountID})
+}
+
+// UpdateRoundUpSettings points round-ups at a goal, or turns them off. The
+// change applies to expenses recorded from now on.
+func (h *Handler) UpdateRoundUpSettings(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req roundUpSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if req.GoalID == nil && req.AccountID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "account_id requires goal_id"})
+		return
+	}
+	if req.GoalID != nil {
+		if _, _, ok := h.goalFor(c, userID, *req.GoalID, savings.CanContribute); !ok {
+			return
+		}
+	}
+	if !h.accountValid(c, userID, req.AccountID) {
+		return
+	}
+
+	if err := h.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"round_up_goal_id":    req.GoalID,
+		"round_up_account_id": req.AccountID,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
+// RoundUpReport totals the round-ups of expenses dated between from and to,
+// both inclusive, month by month. It defaults to the last six months.
+func (h *Handler) RoundUpReport(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := time.Date(now.Year(), now.Month()-5, 1, 0, 0, 0, 0, time.UTC)
+	toParam, err := queryDate(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if toParam != nil {
+		to = *toParam
+	}
+	fromParam, err := queryDate(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fromParam != nil {
+		from = *fromParam
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to"})
+		return
+	}
+
+	var months []roundUpMonth
+	if err := h.DB.Model(&models.RoundUp{}).
+		Select("to_char(date, 'YYYY-MM') AS month, COUNT(*) AS count, SUM(amount) AS total, "+
+			"COALESCE(SUM(amount) FILTER (WHERE contribution_id IS NULL), 0) AS pending").
+		Where("user_id = ? AND date >= ? AND date < ?", userID, from, to.AddDate(0, 0, 1)).
+		Group("month").
+		Order("month").
+		Scan(&months).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	report := roundUpReport{
+		From:   from.Format("2006-01-02"),
+		To:     to.Format("2006-01-02"),
+		Months: make([]roundUpMonth, 0, len(months)),
+	}
+	var total, pending float64
+	for _, m := range months {
+		m.Total, m.Pending = roundCents(m.Total), roundCents(m.Pending)
+		report.Months = append(report.Months, m)
+		report.Count += m.Count
+		total += m.Total
+		pending += m.Pending
+	}
+	report.Total = roundCents(total)
+	report.Pending = roundCents(pending)
+	report.Contributed = roundCents(total - pending)
+	c.JSON(http.StatusOK, report)
+}
+
+// recordRoundUps sets aside the spare change of new expenses when the user
+// has round-ups on. Transfers are not spending and are not rounded up.
+func recordRoundUps(db *gorm.DB, txs []models.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	var user models.User
+	if err := db.Select("round_up_goal_id").First(&user, "id = ?", txs[0].UserID).Error; err != nil {
+		return err
+	}
+	if user.RoundUpGoalID == nil {
+		return nil
+	}
+
+	var roundUps []models.RoundUp
+	for _, t := range txs {
+		if t.Type != "expense" || t.IsTransfer {
+			continue
+		}
+		if amount := savings.RoundUp(t.Amount); amount > 0 {
+			roundUps = append(roundUps, models.RoundUp{
+				UserID:        t.UserID,
+				TransactionID: t.ID,
+				SavingsGoalID: *user.RoundUpGoalID,
+				Amount:        amount,
+				Date:          t.TransactionDate,
+			})
+		}
+	}
+	if len(roundUps) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&roundUps, 500).Error
+}
+
+// sweepRoundUps puts the round-ups recorded before today into their goals,
+// one contribution per user and goal. Round-ups whose expense has since
+// been deleted, or whose import was undone, are dropped first.
+func (h *Handler) sweepRoundUps(now time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if err := h.DB.Where("contribution_id IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM transactions t WHERE t.id = round_ups.transaction_id)").
+		Delete(&models.RoundUp{}).Error; err != nil {
+		log.Printf("round-ups: %v", err)
+		return
+	}
+
+	var groups []struct {
+		UserID        uuid.UUID
+		SavingsGoalID uuid.UUID
+	}
+	if err := h.DB.Model(&models.RoundUp{}).
+		Distinct("user_id", "savings_goal_id").
+		Where("contribution_id IS NULL AND created_at < ?", today).
+		Scan(&groups).Error; err != nil {
+		log.Printf("round-ups: %v", err)
+		return
+	}
+	for _, g := range groups {
+		if err := h.sweepRoundUpGroup(g.UserID, g.SavingsGoalID, today); err != nil {
+			log.Printf("round-ups for user %s: %v", g.UserID, err)
+		}
+	}
+}
+
+// sweepRoundUpGroup contributes one user's pending round-ups for one goal.
+// The round-ups are locked, skipping any another worker holds, and marked
+// with the contribution in the same transaction, so each is swept once.
+func (h *Handler) sweepRoundUpGroup(userID, goalID uuid.UUID, today time.Time) error {
+	return h.DB.Transaction(func(tx *gorm.DB) error {
+		var pending []models.RoundUp
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("user_id = ? AND savings_goal_id = ? AND contribution_id IS NULL AND created_at < ?", userID, goalID, today).
+			Find(&pending).Error; err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(pending))
+		var total float64
+		for i, r := range pending {
+			ids[i] = r.ID
+			total += r.Amount
+		}
+
+		var user models.User
+		if err := tx.Select("round_up_account_id").First(&user, "id = ?", userID).Error; err != nil {
+			return err
+		}
+		accountID := user.RoundUpAccountID
+		if accountID != nil {
+			var count int64
+			if err := tx.Model(&models.Account{}).Where("id = ? AND user_id = ?", *accountID, userID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				accountID = nil
+			}
+		}
+
+		contribution := models.SavingsContribution{
+			UserID:        userID,
+			SavingsGoalID: goalID,
+			AccountID:     accountID,
+			Amount:        roundCents(total),
+			Date:          today,
+			Note:          fmt.Sprintf("Round-ups from %d expenses", len(pending)),
+		}
+		if _, err := h.saveContribution(tx, &contribution); err != nil {
+			if errors.Is(err, errGoalNotFound) || errors.Is(err, errGoalForbidden) {
+				return tx.Where("id IN ?", ids).Delete(&models.RoundUp{}).Error
+			}
+			return err
+		}
+		return tx.Model(&models.RoundUp{}).Where("id IN ?", ids).Update("contribution_id", contribution.ID).Error
+	})
+}
@@ -0,0 +1,410 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/rules"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ruleTestDefaultLimit = 50
+	ruleTestMaxLimit     = 200
+)
+
+type ruleRequest struct {
+	Name            string     `json:"name"`
+	Priority        int        `json:"priority"`
+	IsActive        *bool      `json:"is_active"`
+	TitleContains   string     `json:"title_contains"`
+	TitleRegex      string     `json:"title_regex"`
+	MinAmount       *float64   `json:"min_amount"`
+	MaxAmount       *float64   `json:"max_amount"`
+	AccountID       *uuid.UUID `json:"account_id"`
+	TransactionType string     `json:"transaction_type"`
+	SetCategory     string     `json:"set_category"`
+	AddTags         []string   `json:"add_tags"`
+	SetMerchant     string     `json:"set_merchant"`
+	MarkTransfer    bool       `json:"mark_transfer"`
+}
+
+type ruleTestRequest struct {
+	RuleID    *uuid.UUID   `json:"rule_id"`
+	Rule      *ruleRequest `json:"rule"`
+	Overwrite bool         `json:"overwrite"`
+	Limit     int          `json:"limit"`
+}
+
+type applyRulesRequest struct {
+	Overwrite bool `json:"overwrite"`
+}
+
+func (r ruleRequest) apply(rule *models.Rule) {
+	rule.Name = r.Name
+	rule.Priority = r.Priority
+	rule.IsActive = r.IsActive == nil || *r.IsActive
+	rule.TitleContains = r.TitleContains
+	rule.TitleRegex = r.TitleRegex
+	rule.MinAmount = r.MinAmount
+	rule.MaxAmount = r.MaxAmount
+	rule.AccountID = r.AccountID
+	rule.TransactionType = r.TransactionType
+	rule.SetCategory = r.SetCategory
+	rule.AddTags = r.AddTags
+	rule.SetMerchant = r.SetMerchant
+	rule.MarkTransfer = r.MarkTransfer
+}
+
+func (h *Handler) ListRules(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var list []models.Rule
+	if err := h.DB.Where("user_id = ?", userID).Order("priority asc, created_at asc").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+func (h *Handler) CreateRule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ruleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	rule := models.Rule{UserID: userID}
+	req.apply(&rule)
+	if rule.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+	if err := rules.Validate(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (h *Handler) UpdateRule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req ruleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	var rule models.Rule
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	req.apply(&rule)
+	if rule.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing fields"})
+		return
+	}
+	if err := rules.Validate(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.DB.Save(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (h *Handler) DeleteRule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Rule{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// TestRule previews a saved or unsaved rule against the user's existing
+// transactions, newest first, without changing anything.
+func (h *Handler) TestRule(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ruleTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+
+	var rule models.Rule
+	switch {
+	case req.RuleID != nil:
+		if err := h.DB.Where("id = ? AND user_id = ?", *req.RuleID, userID).First(&rule).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+	case req.Rule != nil:
+		req.Rule.apply(&rule)
+		if err := rules.Validate(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing rule"})
+		return
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = ruleTestDefaultLimit
+	}
+	if limit > ruleTestMaxLimit {
+		limit = ruleTestMaxLimit
+	}
+
+	// The preview runs the rule on its own and regardless of is_active.
+	rule.IsActive = true
+	engine, err := rules.New([]models.Rule{rule})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	matches := []gin.H{}
+	total := 0
+	var batch []models.Transaction
+	err = h.DB.Where("user_id = ?", userID).
+		Order("transaction_date desc, id").
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, t := range batch {
+				after := t
+				after.Tags = append([]string(nil), t.Tags...)
+				if len(engine.Apply(&after, req.Overwrite)) == 0 {
+					continue
+				}
+				total++
+				if len(matches) < limit {
+					matches = append(matches, gin.H{"before": t, "after": after})
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total_matches": total,
+		"matches":       matches,
+	})
+}
+
+// ruleRunTimeout is how long a run may go without saving progress before
+// it is taken to have died with the server and marked failed.
+const ruleRunTimeout = 15 * time.Minute
+
+// ApplyRules starts a background run of the user's active rules over all
+// existing transactions. Progress is read from GetRuleRun.
+func (h *Handler) ApplyRules(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req applyRulesRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+	}
+
+	if err := failStaleRuleRuns(h.DB, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	run := models.RuleRun{
+		UserID:    userID,
+		Status:    "running",
+		Overwrite: req.Overwrite,
+		StartedAt: time.Now(),
+	}
+	if err := h.DB.Create(&run).Error; err != nil {
+		if isUniqueViolation(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "rules are already being applied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	go h.runRules(run)
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+func (h *Handler) GetRuleRun(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var run models.RuleRun
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&run).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// runRules re-applies the user's rules to their whole history in batches,
+// saving only transactions the rules changed, and records the outcome on
+// the run, even if it panics.
+func (h *Handler) runRules(run models.RuleRun) {
+	var err error
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+		h.finishRuleRun(&run, err)
+	}()
+
+	var engine *rules.Engine
+	engine, err = h.loadRules(h.DB, run.UserID)
+	if err == nil {
+		var batch []models.Transaction
+		err = h.DB.Where("user_id = ? AND status <> ?", run.UserID, "reconciled").
+			Order("id").
+			FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+				for i := range batch {
+					run.Scanned++
+					before := ruleFields(batch[i])
+					engine.Apply(&batch[i], run.Overwrite)
+					after := ruleFields(batch[i])
+					if before == after {
+						continue
+					}
+					if err := h.DB.Model(&batch[i]).
+						Select("category", "merchant", "tags", "is_transfer").
+						Updates(&batch[i]).Error; err != nil {
+						return err
+					}
+					run.Updated++
+				}
+				return h.DB.Model(&run).Updates(map[string]interface{}{
+					"scanned": run.Scanned,
+					"updated": run.Updated,
+				}).Error
+			}).Error
+	}
+}
+
+// finishRuleRun records the run's outcome: completed, or failed with err.
+func (h *Handler) finishRuleRun(run *models.RuleRun, err error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       "completed",
+		"scanned":      run.Scanned,
+		"updated":      run.Updated,
+		"completed_at": now,
+	}
+	if err != nil {
+		log.Printf("rule run %s failed: %v", run.ID, err)
+		updates["status"] = "failed"
+		updates["error"] = err.Error()
+	}
+	if err := h.DB.Model(run).Updates(updates).Error; err != nil {
+		log.Printf("rule run %s: saving status: %v", run.ID, err)
+	}
+}
+
+// failStaleRuleRuns marks the user's running runs that have not saved
+// progress within ruleRunTimeout as failed, so a run lost to a crash or
+// restart does not block new ones.
+func failStaleRuleRuns(db *gorm.DB, userID uuid.UUID) error {
+	now := time.Now()
+	return db.Model(&models.RuleRun{}).
+		Where("user_id = ? AND status = ? AND updated_at < ?", userID, "running", now.Add(-ruleRunTimeout)).
+		Updates(map[string]interface{}{
+			"status":       "failed",
+			"error":        "run stopped without finishing",
+			"completed_at": now,
+		}).Error
+}
+
+// loadRules builds the rule engine for a user's active rules.
+func (h *Handler) loadRules(db *gorm.DB, userID uuid.UUID) (*rules.Engine, error) {
+	var list []models.Rule
+	if err := db.Where("user_id = ? AND is_active = true", userID).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return rules.New(list)
+}
+
+// ruleFields is the part of a transaction that rules can change, in a
+// comparable form.
+func ruleFields(t models.Transaction) [4]interface{} {
+	tags := ""
+	for _, tag := range t.Tags {
+		tags += tag + "\x00"
+	}
+	return [4]interface{}{t.Category, t.Merchant, tags, t.IsTransfer}
+}
@@ -2,23 +2,48 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"dirav-backend/internal/models"
+	"dirav-backend/internal/savings"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type savingsRequest struct {
 	Name         string  `json:"name"`
 	TargetAmount float64 `json:"target_amount"`
 	Deadline     string  `json:"deadline"`
+	Priority     int     `json:"priority"`
+	Category     string  `json:"category"`
 }
 
-type contributeRequest struct {
-	Amount float64 `json:"amount"`
+type savingsGoalView struct {
+	models.SavingsGoal
+	// Role is the user's role on the goal: owner for their own goals.
+	Role       string             `json:"role"`
+	Projection savings.Projection `json:"projection"`
 }
 
+type savingsProjectionView struct {
+	SavingsGoalID uuid.UUID `json:"savings_goal_id"`
+	savings.Projection
+}
+
+var savingsList = listSpec{
+	sorts: map[string]sortField{
+		"name":           {column: "name", field: "Name", kind: "text"},
+		"target_amount":  {column: "target_amount", field: "TargetAmount", kind: "number"},
+		"current_amount": {column: "current_amount", field: "CurrentAmount", kind: "number"},
+		"priority":       {column: "priority", field: "Priority", kind: "number"},
+		"created_at":     {column: "created_at", field: "CreatedAt", kind: "time"},
+	},
+	defaultSort: "-created_at",
+}
+
+// ListSavings returns the user's own goals and the goals shared with them.
 func (h *Handler) ListSavings(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
@@ -26,12 +51,121 @@ func (h *Handler) ListSavings(c *gin.Context) {
 		return
 	}
 
+	shared := h.DB.Model(&models.SavingsMember{}).Select("savings_goal_id").Where("user_id = ?", userID)
+	query := h.DB.Where("(user_id = ? OR id IN (?))", userID, shared)
+	switch c.Query("completed") {
+	case "true":
+		query = query.Where("is_completed = true")
+	case "false":
+		query = query.Where("is_completed = false")
+	}
+	if category := c.Query("category"); category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("name ILIKE ?", likePattern(q))
+	}
+
+	p, err := parsePage(c, savingsList)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	var goals []models.SavingsGoal
-	if err := h.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&goals).Error; err != nil {
+	if err := p.apply(query).Find(&goals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	goals, next := finishPage(c, p, goals)
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	projections, err := savingsProjections(h.DB, goals, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	roles, err := goalRoles(h.DB, goals, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	views := make([]savingsGoalView, len(goals))
+	for i, g := range goals {
+		views[i] = savingsGoalView{SavingsGoal: g, Role: roles[g.ID], Projection: projections[i]}
+	}
+	writePage(c, p, views, next)
+}
+
+// GetSavingsProjection reports what a goal needs to meet its deadline and
+// when it is reached at the pace saved so far.
+func (h *Handler) GetSavingsProjection(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	goal, _, ok := h.goalFor(c, userID, id, savings.CanView)
+	if !ok {
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	projections, err := savingsProjections(h.DB, []models.SavingsGoal{goal}, now)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
-	c.JSON(http.StatusOK, goals)
+	c.JSON(http.StatusOK, savingsProjectionView{SavingsGoalID: goal.ID, Projection: projections[0]})
+}
+
+// savingsProjections projects the goals, in order, from what was
+// contributed to each over the pace window, read in one grouped query.
+func savingsProjections(db *gorm.DB, goals []models.SavingsGoal, now time.Time) ([]savings.Projection, error) {
+	out := make([]savings.Projection, len(goals))
+	if len(goals) == 0 {
+		return out, nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	ids := make([]uuid.UUID, len(goals))
+	for i, g := range goals {
+		ids[i] = g.ID
+	}
+
+	var rows []struct {
+		SavingsGoalID uuid.UUID
+		Saved         float64
+	}
+	if err := db.Model(&models.SavingsContribution{}).
+		Select("savings_goal_id, SUM(amount) AS saved").
+		Where("savings_goal_id IN ? AND date > ?", ids, today.AddDate(0, 0, -savings.PaceWindow)).
+		Group("savings_goal_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	saved := make(map[uuid.UUID]float64, len(rows))
+	for _, r := range rows {
+		saved[r.SavingsGoalID] = r.Saved
+	}
+
+	for i, g := range goals {
+		out[i] = savings.Project(g, saved[g.ID], savings.PaceDays(g.CreatedAt, today), today)
+	}
+	return out, nil
 }
 
 func (h *Handler) CreateSavings(c *gin.Context) {
@@ -46,6 +180,10 @@ func (h *Handler) CreateSavings(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
+	if req.Priority < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must not be negative"})
+		return
+	}
 
 	var deadline *time.Time
 	if req.Deadline != "" {
@@ -62,6 +200,8 @@ func (h *Handler) CreateSavings(c *gin.Context) {
 		Name:         req.Name,
 		TargetAmount: req.TargetAmount,
 		Deadline:     deadline,
+		Priority:     req.Priority,
+		Category:     strings.TrimSpace(req.Category),
 	}
 
 	if err := h.DB.Create(&goal).Error; err != nil {
@@ -90,10 +230,21 @@ func (h *Handler) UpdateSavings(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
+	if req.Priority < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "priority must not be negative"})
+		return
+	}
+	if _, _, ok := h.goalFor(c, userID, id, savings.CanManage); !ok {
+		return
+	}
 
+	// A new target can complete a goal or reopen it.
 	updates := map[string]interface{}{
 		"name":          req.Name,
 		"target_amount": req.TargetAmount,
+		"is_completed":  gorm.Expr("current_amount >= ?", req.TargetAmount),
+		"priority":      req.Priority,
+		"category":      strings.TrimSpace(req.Category),
 	}
 
 	if req.Deadline != "" {
@@ -106,7 +257,7 @@ func (h *Handler) UpdateSavings(c *gin.Context) {
 	}
 
 	if err := h.DB.Model(&models.SavingsGoal{}).
-		Where("id = ? AND user_id = ?", id, userID).
+		Where("id = ?", id).
 		Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
@@ -128,48 +279,69 @@ func (h *Handler) DeleteSavings(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.SavingsGoal{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	if _, _, ok := h.goalFor(c, userID, id, savings.CanManage); !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
-}
-
-func (h *Handler) ContributeSavings(c *gin.Context) {
-	userID, err := getUserID(c)
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("id = ?", id).Delete(&models.SavingsGoal{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		if err := tx.Where("savings_goal_id = ?", id).Delete(&models.SavingsMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("savings_goal_id = ?", id).Delete(&models.SavingsInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("savings_goal_id = ?", id).Delete(&models.SavingsContribution{}).Error; err != nil {
+			return err
+		}
+		schedules := tx.Model(&models.ContributionSchedule{}).Select("id").Where("savings_goal_id = ?", id)
+		if err := tx.Where("schedule_id IN (?)", schedules).Delete(&models.ContributionRun{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("savings_goal_id = ?", id).Delete(&models.ContributionSchedule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("savings_goal_id = ?", id).Delete(&models.RoundUp{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("round_up_goal_id = ?", id).
+			Updates(map[string]interface{}{"round_up_goal_id": nil, "round_up_account_id": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Where("savings_goal_id = ?", id).Delete(&models.PlanAllocation{}).Error
+	})
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
 
-	id, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
 
-	var req contributeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
-		return
+// goalRoles returns the user's role on each of the goals.
+func goalRoles(db *gorm.DB, goals []models.SavingsGoal, userID uuid.UUID) (map[uuid.UUID]string, error) {
+	roles := make(map[uuid.UUID]string, len(goals))
+	var shared []uuid.UUID
+	for _, g := range goals {
+		if g.UserID == userID {
+			roles[g.ID] = savings.RoleOwner
+		} else {
+			shared = append(shared, g.ID)
+		}
 	}
-
-	var goal models.SavingsGoal
-	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&goal).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-		return
+	if len(shared) == 0 {
+		return roles, nil
 	}
 
-	goal.CurrentAmount += req.Amount
-	if goal.CurrentAmount >= goal.TargetAmount {
-		goal.IsCompleted = true
+	var members []models.SavingsMember
+	if err := db.Where("user_id = ? AND savings_goal_id IN ?", userID, shared).Find(&members).Error; err != nil {
+		return nil, err
 	}
-
-	if err := h.DB.Save(&goal).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
-		return
+	for _, m := range members {
+		roles[m.SavingsGoalID] = m.Role
 	}
-
-	c.JSON(http.StatusOK, goal)
+	return roles, nil
 }
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/planning"
+	"dirav-backend/internal/savings"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// allocationNote is the note on contributions made by an allocation.
+const allocationNote = "Allocated surplus"
+
+var (
+	errSurplusAllocated = errors.New("surplus already allocated")
+	errNothingAllocated = errors.New("no goals to allocate to")
+)
+
+type allocateRequest struct {
+	// Amount left out allocates the surplus of Month.
+	Amount *float64 `json:"amount"`
+	// Month is YYYY-MM and defaults to the previous month.
+	Month    string `json:"month"`
+	Strategy string `json:"strategy"`
+	// Category limits the allocation to goals in it.
+	Category         string     `json:"category"`
+	AccountID        *uuid.UUID `json:"account_id"`
+	SavingsAccountID *uuid.UUID `json:"savings_account_id"`
+	DryRun           bool       `json:"dry_run"`
+}
+
+type allocationView struct {
+	SavingsGoalID uuid.UUID `json:"savings_goal_id"`
+	Name          string    `json:"name"`
+	Category      string    `json:"category"`
+	Priority      int       `json:"priority"`
+	Amount        float64   `json:"amount"`
+	// Remaining is what the goal still lacks after the allocation.
+	Remaining      float64    `json:"remaining"`
+	ContributionID *uuid.UUID `json:"contribution_id"`
+}
+
+type allocationResult struct {
+	Strategy string `json:"strategy"`
+	// Month is set when the amount is the month's surplus.
+	Month       *string          `json:"month"`
+	Amount      float64          `json:"amount"`
+	Allocated   float64          `json:"allocated"`
+	Unallocated float64          `json:"unallocated"`
+	DryRun      bool             `json:"dry_run"`
+	Allocations []allocationView `json:"allocations"`
+}
+
+// AllocateSavings splits an amount, by default last month's surplus,
+// between the goals the user can contribute to. With dry_run it only
+// reports the split; otherwise each share is contributed, all or nothing.
+// A month's surplus is allocated once; the contributions are transfers, so
+// the surplus itself never shrinks.
+func (h *Handler) AllocateSavings(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req allocateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if !savings.ValidStrategy(req.Strategy) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strategy must be priority, proportional or deadline"})
+		return
+	}
+	if req.SavingsAccountID != nil {
+		if req.AccountID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "savings_account_id requires account_id"})
+			return
+		}
+		if *req.SavingsAccountID == *req.AccountID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "account_id and savings_account_id must differ"})
+			return
+		}
+	}
+	if !h.accountValid(c, userID, req.AccountID) || !h.accountValid(c, userID, req.SavingsAccountID) {
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	result := allocationResult{Strategy: req.Strategy, DryRun: req.DryRun, Allocations: []allocationView{}}
+	var month time.Time
+
+	if req.Amount != nil {
+		if !savings.ValidAmount(*req.Amount) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": savings.ErrInvalidAmount.Error()})
+			return
+		}
+		result.Amount = *req.Amount
+	} else {
+		month = time.Date(today.Year(), today.Month()-1, 1, 0, 0, 0, 0, time.UTC)
+		if req.Month != "" {
+			if month, err = planning.ParseMonth(req.Month); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		surplus, err := monthSurplus(h.DB, userID, month)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if surplus <= 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "no surplus to allocate", "surplus": surplus})
+			return
+		}
+		label := month.Format(planning.MonthLayout)
+		result.Amount, result.Month = surplus, &label
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		goals, err := allocatableGoals(tx, userID, req.Category, !req.DryRun)
+		if err != nil {
+			return err
+		}
+
+		shares, left := savings.Allocate(goals, result.Amount, req.Strategy, today)
+		for i, g := range goals {
+			view := allocationView{
+				SavingsGoalID: g.ID,
+				Name:          g.Name,
+				Category:      g.Category,
+				Priority:      g.Priority,
+				Amount:        shares[i],
+				Remaining:     roundCents(max(0, g.TargetAmount-g.CurrentAmount-shares[i])),
+			}
+			if shares[i] > 0 && !req.DryRun {
+				contribution := models.SavingsContribution{
+					UserID:           userID,
+					SavingsGoalID:    g.ID,
+					AccountID:        req.AccountID,
+					SavingsAccountID: req.SavingsAccountID,
+					Amount:           shares[i],
+					Date:             today,
+					Note:             allocationNote,
+				}
+				if _, err := h.saveContribution(tx, &contribution); err != nil {
+					return err
+				}
+				view.ContributionID = &contribution.ID
+			}
+			result.Allocations = append(result.Allocations, view)
+			result.Allocated += shares[i]
+		}
+		result.Allocated, result.Unallocated = roundCents(result.Allocated), left
+
+		if req.DryRun {
+			return nil
+		}
+		// Recording nothing keeps the month open for when there are goals.
+		if result.Allocated == 0 {
+			return errNothingAllocated
+		}
+		if result.Month == nil {
+			return nil
+		}
+		record := models.SurplusAllocation{
+			UserID:    userID,
+			Month:     month,
+			Strategy:  req.Strategy,
+			Amount:    result.Amount,
+			Allocated: result.Allocated,
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errSurplusAllocated
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errSurplusAllocated):
+		c.JSON(http.StatusConflict, gin.H{"error": "surplus for " + *result.Month + " already allocated"})
+		return
+	case errors.Is(err, errNothingAllocated):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errGoalNotFound), errors.Is(err, errGoalForbidden):
+		writeGoalError(c, err)
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// allocatableGoals returns the open goals the user can contribute to,
+// optionally in one category, in the order the priority strategy funds
+// them. With lock they stay locked until tx ends.
+func allocatableGoals(tx *gorm.DB, userID uuid.UUID, category string, lock bool) ([]models.SavingsGoal, error) {
+	shared := tx.Model(&models.SavingsMember{}).Select("savings_goal_id").
+		Where("user_id = ? AND role = ?", userID, savings.RoleContributor)
+	query := tx.Where("(user_id = ? OR id IN (?)) AND is_completed = false", userID, shared)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if lock {
+		query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var goals []models.SavingsGoal
+	err := query.Order("priority, deadline NULLS LAST, created_at").Find(&goals).Error
+	return goals, err
+}
+
+// monthSurplus is the month's income less its spending, transfers left out.
+func monthSurplus(db *gorm.DB, userID uuid.UUID, month time.Time) (float64, error) {
+	start, end := planning.MonthRange(month)
+	var surplus float64
+	err := db.Model(&models.Transaction{}).
+		Select("COALESCE(SUM(CASE WHEN type = 'income' THEN amount ELSE -amount END), 0)").
+		Where("user_id = ? AND type IN ? AND is_transfer = false", userID, []string{"income", "expense"}).
+		Where("transaction_date >= ? AND transaction_date < ?", start, end).
+		Scan(&surplus).Error
+	return roundCents(surplus), err
+}
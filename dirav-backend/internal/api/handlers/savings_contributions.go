@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/savings"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type contributeRequest struct {
+	Amount float64 `json:"amount"`
+	// Date defaults to today.
+	Date string `json:"date"`
+	Note string `json:"note"`
+	// AccountID is where the money comes from, or goes to on withdrawal.
+	AccountID *uuid.UUID `json:"account_id"`
+	// SavingsAccountID is the account the goal's money is kept in, if any.
+	SavingsAccountID *uuid.UUID `json:"savings_account_id"`
+}
+
+type savingsContributionResult struct {
+	models.SavingsGoal
+	Contribution models.SavingsContribution `json:"contribution"`
+}
+
+var (
+	errGoalNotFound        = errors.New("savings goal not found")
+	errInsufficientSavings = errors.New("insufficient savings")
+)
+
+var contributionList = listSpec{
+	sorts: map[string]sortField{
+		"date":       {column: "date", field: "Date", kind: "time"},
+		"amount":     {column: "amount", field: "Amount", kind: "number"},
+		"created_at": {column: "created_at", field: "CreatedAt", kind: "time"},
+	},
+	defaultSort: "-date",
+}
+
+// ListSavingsContributions returns a goal's contributions and withdrawals.
+func (h *Handler) ListSavingsContributions(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if _, _, ok := h.goalFor(c, userID, id, savings.CanView); !ok {
+		return
+	}
+
+	listPage[models.SavingsContribution](c, h.DB.Where("savings_goal_id = ?", id), contributionList)
+}
+
+// ContributeSavings puts money into a goal. The owner and contributors may
+// contribute.
+func (h *Handler) ContributeSavings(c *gin.Context) {
+	h.changeSavings(c, 1)
+}
+
+// WithdrawSavings takes money out of a goal, reopening it when it drops
+// below its target. Only the owner may withdraw.
+func (h *Handler) WithdrawSavings(c *gin.Context) {
+	h.changeSavings(c, -1)
+}
+
+// changeSavings records a contribution, or a withdrawal when sign is -1.
+func (h *Handler) changeSavings(c *gin.Context, sign float64) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req contributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if !savings.ValidAmount(req.Amount) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": savings.ErrInvalidAmount.Error()})
+		return
+	}
+
+	now, err := h.userNow(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if req.Date != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
+			return
+		}
+		if parsed.After(date) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date cannot be in the future"})
+			return
+		}
+		date = parsed
+	}
+	if req.SavingsAccountID != nil {
+		if req.AccountID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "savings_account_id requires account_id"})
+			return
+		}
+		if *req.SavingsAccountID == *req.AccountID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "account_id and savings_account_id must differ"})
+			return
+		}
+	}
+	if !h.accountValid(c, userID, req.AccountID) || !h.accountValid(c, userID, req.SavingsAccountID) {
+		return
+	}
+
+	contribution := models.SavingsContribution{
+		UserID:           userID,
+		SavingsGoalID:    id,
+		AccountID:        req.AccountID,
+		SavingsAccountID: req.SavingsAccountID,
+		Amount:           sign * req.Amount,
+		Date:             date,
+		Note:             req.Note,
+	}
+	var goal models.SavingsGoal
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		goal, err = h.saveContribution(tx, &contribution)
+		return err
+	})
+	switch {
+	case errors.Is(err, errGoalNotFound), errors.Is(err, errGoalForbidden):
+		writeGoalError(c, err)
+		return
+	case errors.Is(err, errInsufficientSavings):
+		c.JSON(http.StatusConflict, gin.H{"error": "withdrawal exceeds the amount saved", "available": goal.CurrentAmount})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, savingsContributionResult{SavingsGoal: goal, Contribution: contribution})
+}
+
+// saveContribution records a contribution and applies it to its goal,
+// which stays locked until tx ends so concurrent changes cannot lose
+// updates or leave IsCompleted stale. The contributing user must be allowed
+// to contribute, or to manage the goal for a withdrawal. When the
+// contribution names accounts the money is moved between them in the same
+// transaction. On errInsufficientSavings the goal is returned as it stands.
+func (h *Handler) saveContribution(tx *gorm.DB, contribution *models.SavingsContribution) (models.SavingsGoal, error) {
+	var goal models.SavingsGoal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&goal, "id = ?", contribution.SavingsGoalID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return goal, errGoalNotFound
+		}
+		return goal, err
+	}
+
+	role, err := goalRole(tx, goal, contribution.UserID)
+	if err != nil {
+		return goal, err
+	}
+	allowed := savings.CanContribute
+	if contribution.Amount < 0 {
+		allowed = savings.CanManage
+	}
+	switch {
+	case !savings.CanView(role):
+		return models.SavingsGoal{}, errGoalNotFound
+	case !allowed(role):
+		return models.SavingsGoal{}, errGoalForbidden
+	}
+
+	before := goal
+	if err := savings.Apply(&goal, contribution.Amount); err != nil {
+		if errors.Is(err, savings.ErrInsufficient) {
+			return before, errInsufficientSavings
+		}
+		return before, err
+	}
+
+	transfers := savingsTransfers(before, *contribution)
+	if err := h.createTransactions(tx, transfers); err != nil {
+		return before, err
+	}
+	for _, t := range transfers {
+		if err := adjustBalance(tx, *t.AccountID, signedAmount(t)); err != nil {
+			return before, err
+		}
+		id := t.ID
+		if *t.AccountID == *contribution.AccountID {
+			contribution.AccountTransactionID = &id
+		} else {
+			contribution.SavingsTransactionID = &id
+		}
+	}
+
+	if err := tx.Create(contribution).Error; err != nil {
+		return before, err
+	}
+	if err := tx.Model(&goal).Updates(map[string]interface{}{
+		"current_amount": goal.CurrentAmount,
+		"is_completed":   goal.IsCompleted,
+	}).Error; err != nil {
+		return before, err
+	}
+	return goal, nil
+}
+
+// savingsTransfers books a contribution on the accounts it names: out of
+// AccountID and into SavingsAccountID, or the other way round for a
+// withdrawal. Both sides are transfers, so they count as neither spending
+// nor income.
+func savingsTransfers(goal models.SavingsGoal, c models.SavingsContribution) []models.Transaction {
+	if c.AccountID == nil {
+		return nil
+	}
+
+	amount, title := c.Amount, "Transfer to "+goal.Name
+	fromType, toType := "expense", "income"
+	if amount < 0 {
+		amount, title = -amount, "Transfer from "+goal.Name
+		fromType, toType = toType, fromType
+	}
+	transfer := func(accountID *uuid.UUID, typ string) models.Transaction {
+		return models.Transaction{
+			UserID:          c.UserID,
+			AccountID:       accountID,
+			Title:           title,
+			Amount:          amount,
+			Type:            typ,
+			Category:        "Savings",
+			TransactionDate: c.Date,
+			Notes:           c.Note,
+			IsTransfer:      true,
+		}
+	}
+
+	txs := []models.Transaction{transfer(c.AccountID, fromType)}
+	if c.SavingsAccountID != nil {
+		txs = append(txs, transfer(c.SavingsAccountID, toType))
+	}
+	return txs
+}
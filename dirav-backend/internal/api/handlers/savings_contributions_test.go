@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestSavingsTransfers(t *testing.T) {
+	goal := models.SavingsGoal{Name: "Laptop"}
+	checking, savingsAccount := uuid.New(), uuid.New()
+	c := models.SavingsContribution{
+		UserID: uuid.New(),
+		Amount: 25,
+		Date:   time.Date(2025, 3, 7, 0, 0, 0, 0, time.UTC),
+	}
+
+	if got := savingsTransfers(goal, c); len(got) != 0 {
+		t.Fatalf("without accounts: got %d transactions, want none", len(got))
+	}
+
+	c.AccountID = &checking
+	got := savingsTransfers(goal, c)
+	if len(got) != 1 || got[0].Type != "expense" || got[0].Amount != 25 || !got[0].IsTransfer || *got[0].AccountID != checking {
+		t.Fatalf("from checking: got %+v, want one 25 expense transfer on checking", got)
+	}
+	if got[0].Title != "Transfer to Laptop" {
+		t.Errorf("title = %q", got[0].Title)
+	}
+
+	c.SavingsAccountID = &savingsAccount
+	c.Amount = -40
+	got = savingsTransfers(goal, c)
+	if len(got) != 2 {
+		t.Fatalf("withdrawal: got %d transactions, want 2", len(got))
+	}
+	if got[0].Type != "income" || *got[0].AccountID != checking || got[0].Amount != 40 {
+		t.Errorf("checking side: %+v, want 40 income", got[0])
+	}
+	if got[1].Type != "expense" || *got[1].AccountID != savingsAccount || got[1].Amount != 40 {
+		t.Errorf("savings side: %+v, want 40 expense", got[1])
+	}
+	if signedAmount(got[0])+signedAmount(got[1]) != 0 {
+		t.Error("a transfer between own accounts should not change the total balance")
+	}
+}
@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"dirav-backend/internal/models"
+	"dirav-backend/internal/notify"
+	"dirav-backend/internal/savings"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Invitation states.
+const (
+	invitationPending  = "pending"
+	invitationAccepted = "accepted"
+	invitationDeclined = "declined"
+	invitationRevoked  = "revoked"
+)
+
+// invitationTTL is how long an invitation can be accepted.
+const invitationTTL = 14 * 24 * time.Hour
+
+var (
+	errGoalForbidden      = errors.New("not allowed on this savings goal")
+	errInvitationNotFound = errors.New("invitation not found")
+	errInvitationClosed   = errors.New("invitation is no longer pending")
+)
+
+type invitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type memberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type savingsMemberView struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	Role        string    `json:"role"`
+	Contributed float64   `json:"contributed"`
+	Withdrawn   float64   `json:"withdrawn"`
+	Net         float64   `json:"net"`
+	// SharePercent is Net as a share of what the goal holds.
+	SharePercent float64 `json:"share_percent"`
+}
+
+type myInvitationView struct {
+	models.SavingsInvitation
+	GoalName      string `json:"goal_name"`
+	InvitedByName string `json:"invited_by_name"`
+}
+
+// goalRole returns the user's role on a goal, or "" when they have none.
+func goalRole(db *gorm.DB, goal models.SavingsGoal, userID uuid.UUID) (string, error) {
+	if goal.UserID == userID {
+		return savings.RoleOwner, nil
+	}
+	var member models.SavingsMember
+	err := db.Where("savings_goal_id = ? AND user_id = ?", goal.ID, userID).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return member.Role, err
+}
+
+// accessGoal loads a goal for a user whose role passes allowed. Goals the
+// user cannot see at all are reported as not found, so their existence is
+// not given away; goals they can see but not act on are forbidden.
+func accessGoal(db *gorm.DB, userID, goalID uuid.UUID, allowed func(string) bool) (models.SavingsGoal, string, error) {
+	var goal models.SavingsGoal
+	if err := db.First(&goal, "id = ?", goalID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return goal, "", errGoalNotFound
+		}
+		return goal, "", err
+	}
+	role, err := goalRole(db, goal, userID)
+	switch {
+	case err != nil:
+		return goal, "", err
+	case !savings.CanView(role):
+		return goal, "", errGoalNotFound
+	case !allowed(role):
+		return goal, role, errGoalForbidden
+	}
+	return goal, role, nil
+}
+
+// goalFor is accessGoal for handlers, writing the error response when the
+// goal cannot be had.
+func (h *Handler) goalFor(c *gin.Context, userID, goalID uuid.UUID, allowed func(string) bool) (models.SavingsGoal, string, bool) {
+	goal, role, err := accessGoal(h.DB, userID, goalID, allowed)
+	if err != nil {
+		writeGoalError(c, err)
+		return goal, role, false
+	}
+	return goal, role, true
+}
+
+func writeGoalError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errGoalNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, errGoalForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+	}
+}
+
+// ListSavingsMembers returns the owner and members of a goal with what
+// each has put in and taken out.
+func (h *Handler) ListSavingsMembers(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	goal, _, ok := h.goalFor(c, userID, id, savings.CanView)
+	if !ok {
+		return
+	}
+
+	var members []models.SavingsMember
+	if err := h.DB.Where("savings_goal_id = ?", id).Order("created_at").Find(&members).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	roles := map[uuid.UUID]string{goal.UserID: savings.RoleOwner}
+	ids := []uuid.UUID{goal.UserID}
+	for _, m := range members {
+		roles[m.UserID] = m.Role
+		ids = append(ids, m.UserID)
+	}
+
+	var users []models.User
+	if err := h.DB.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	byID := make(map[uuid.UUID]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	var totals []struct {
+		UserID      uuid.UUID
+		Contributed float64
+		Withdrawn   float64
+	}
+	if err := h.DB.Model(&models.SavingsContribution{}).
+		Select("user_id, COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0) AS contributed, "+
+			"COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0) AS withdrawn").
+		Where("savings_goal_id = ?", id).
+		Group("user_id").
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	views := make([]savingsMemberView, 0, len(ids))
+	index := make(map[uuid.UUID]int, len(ids))
+	for _, uid := range ids {
+		u := byID[uid]
+		index[uid] = len(views)
+		views = append(views, savingsMemberView{
+			UserID:    uid,
+			Email:     u.Email,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Role:      roles[uid],
+		})
+	}
+	for _, t := range totals {
+		i, ok := index[t.UserID]
+		if !ok {
+			continue
+		}
+		v := &views[i]
+		v.Contributed = roundCents(t.Contributed)
+		v.Withdrawn = roundCents(t.Withdrawn)
+		v.Net = roundCents(t.Contributed - t.Withdrawn)
+		if goal.CurrentAmount > 0 {
+			v.SharePercent = roundCents(v.Net / goal.CurrentAmount * 100)
+		}
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// UpdateSavingsMember changes a member's role.
+func (h *Handler) UpdateSavingsMember(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	var req memberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if !savings.ValidMemberRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be contributor or viewer"})
+		return
+	}
+	if _, _, ok := h.goalFor(c, userID, id, savings.CanManage); !ok {
+		return
+	}
+
+	res := h.DB.Model(&models.SavingsMember{}).
+		Where("savings_goal_id = ? AND user_id = ?", id, memberID).
+		Update("role", req.Role)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "updated"})
+}
+
+// RemoveSavingsMember takes a member off a goal. The owner can remove
+// anyone; members can remove themselves.
+func (h *Handler) RemoveSavingsMember(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return
+	}
+
+	allowed := savings.CanManage
+	if memberID == userID {
+		allowed = savings.CanView
+	}
+	if _, _, ok := h.goalFor(c, userID, id, allowed); !ok {
+		return
+	}
+
+	res := h.DB.Where("savings_goal_id = ? AND user_id = ?", id, memberID).Delete(&models.SavingsMember{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "member not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// CreateSavingsInvitation invites someone by email to join a goal and
+// sends them the invitation.
+func (h *Handler) CreateSavingsInvitation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var req invitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if at := strings.Index(email, "@"); at < 1 || at == len(email)-1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email"})
+		return
+	}
+	if !savings.ValidMemberRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be contributor or viewer"})
+		return
+	}
+	goal, _, ok := h.goalFor(c, userID, id, savings.CanManage)
+	if !ok {
+		return
+	}
+
+	var invitee models.User
+	err = h.DB.Where("lower(email) = ?", email).First(&invitee).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if err == nil {
+		role, err := goalRole(h.DB, goal, invitee.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if role != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "already a member"})
+			return
+		}
+	}
+
+	now := time.Now()
+	var pending int64
+	if err := h.DB.Model(&models.SavingsInvitation{}).
+		Where("savings_goal_id = ? AND email = ? AND status = ? AND expires_at > ?", id, email, invitationPending, now).
+		Count(&pending).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if pending > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "already invited"})
+		return
+	}
+
+	invitation := models.SavingsInvitation{
+		SavingsGoalID: id,
+		InvitedBy:     userID,
+		Email:         email,
+		Role:          req.Role,
+		Status:        invitationPending,
+		ExpiresAt:     now.Add(invitationTTL),
+	}
+	if err := h.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	var inviter models.User
+	h.DB.Select("first_name", "last_name").First(&inviter, "id = ?", userID)
+	if err := h.notifier().Notify(context.Background(), invitationMessage(invitation, goal, inviter, invitee.ID)); err != nil {
+		log.Printf("savings invitation %s: delivery failed: %v", invitation.ID, err)
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// ListSavingsInvitations returns a goal's invitations, newest first.
+func (h *Handler) ListSavingsInvitations(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if _, _, ok := h.goalFor(c, userID, id, savings.CanManage); !ok {
+		return
+	}
+
+	var invitations []models.SavingsInvitation
+	if err := h.DB.Where("savings_goal_id = ?", id).Order("created_at desc").Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeSavingsInvitation withdraws a pending invitation.
+func (h *Handler) RevokeSavingsInvitation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	invitationID, err := uuid.Parse(c.Param("invitation_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invitation_id"})
+		return
+	}
+	if _, _, ok := h.goalFor(c, userID, id, savings.CanManage); !ok {
+		return
+	}
+
+	res := h.DB.Model(&models.SavingsInvitation{}).
+		Where("id = ? AND savings_goal_id = ? AND status = ?", invitationID, id, invitationPending).
+		Updates(map[string]interface{}{"status": invitationRevoked, "responded_at": time.Now()})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListMyInvitations returns the pending invitations sent to the user's
+// email address.
+func (h *Handler) ListMyInvitations(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Select("email").First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	var views []myInvitationView
+	if err := h.DB.Table("savings_invitations i").
+		Select("i.*, g.name AS goal_name, trim(u.first_name || ' ' || u.last_name) AS invited_by_name").
+		Joins("JOIN savings_goals g ON g.id = i.savings_goal_id").
+		Joins("JOIN users u ON u.id = i.invited_by").
+		Where("i.email = ? AND i.status = ? AND i.expires_at > ?", strings.ToLower(user.Email), invitationPending, time.Now()).
+		Order("i.created_at desc").
+		Scan(&views).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if views == nil {
+		views = []myInvitationView{}
+	}
+	c.JSON(http.StatusOK, views)
+}
+
+// AcceptSavingsInvitation joins the goal with the invited role.
+func (h *Handler) AcceptSavingsInvitation(c *gin.Context) {
+	h.respondToInvitation(c, invitationAccepted)
+}
+
+func (h *Handler) DeclineSavingsInvitation(c *gin.Context) {
+	h.respondToInvitation(c, invitationDeclined)
+}
+
+// respondToInvitation accepts or declines an invitation to the user's
+// email address. The invitation is locked so it is answered once.
+func (h *Handler) respondToInvitation(c *gin.Context, status string) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Select("id", "email").First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+
+	var invitation models.SavingsInvitation
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND email = ?", id, strings.ToLower(user.Email)).
+			First(&invitation).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errInvitationNotFound
+			}
+			return err
+		}
+		if invitation.Status != invitationPending || !invitation.ExpiresAt.After(time.Now()) {
+			return errInvitationClosed
+		}
+
+		if status == invitationAccepted {
+			var goal models.SavingsGoal
+			if err := tx.First(&goal, "id = ?", invitation.SavingsGoalID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errInvitationNotFound
+				}
+				return err
+			}
+			if goal.UserID != userID {
+				member := models.SavingsMember{SavingsGoalID: goal.ID, UserID: userID, Role: invitation.Role}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&member).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		now := time.Now()
+		invitation.Status, invitation.RespondedAt = status, &now
+		return tx.Model(&invitation).Updates(map[string]interface{}{"status": status, "responded_at": now}).Error
+	})
+	switch {
+	case errors.Is(err, errInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, errInvitationClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "invitation is " + invitationState(invitation)})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+// invitationState is the invitation's status, or expired when a pending
+// one has run out.
+func invitationState(i models.SavingsInvitation) string {
+	if i.Status == invitationPending {
+		return "expired"
+	}
+	return i.Status
+}
+
+func invitationMessage(i models.SavingsInvitation, goal models.SavingsGoal, inviter models.User, inviteeID uuid.UUID) notify.Message {
+	name := strings.TrimSpace(inviter.FirstName + " " + inviter.LastName)
+	if name == "" {
+		name = "Someone"
+	}
+	return notify.Message{
+		UserID:  inviteeID,
+		Email:   i.Email,
+		Kind:    "savings_invitation",
+		Subject: fmt.Sprintf("%s invited you to save for %s", name, goal.Name),
+		Body: fmt.Sprintf("%s invited you to join the savings goal %q as a %s. Sign in with %s to accept before %s.",
+			name, goal.Name, i.Role, i.Email, i.ExpiresAt.Format("2 January 2006")),
+		Ref: i.ID,
+	}
+}
@@ -1,22 +1,61 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"strconv"
 	"time"
 
 	"dirav-backend/internal/models"
+	"dirav-backend/internal/search"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type transactionRequest struct {
-	AccountID *uuid.UUID `json:"account_id"`
-	Title     string     `json:"title"`
-	Amount    float64    `json:"amount"`
-	Type      string     `json:"type"`
-	Category  string     `json:"category"`
-	Date      string     `json:"date"`
+	AccountID   *uuid.UUID `json:"account_id"`
+	Title       string     `json:"title"`
+	Amount      float64    `json:"amount"`
+	Type        string     `json:"type"`
+	Category    string     `json:"category"`
+	Date        string     `json:"date"`
+	Merchant    string     `json:"merchant"`
+	Tags        []string   `json:"tags"`
+	IsTransfer  bool       `json:"is_transfer"`
+	Attachments []string   `json:"attachments"`
+	Status      string     `json:"status"`
+}
+
+var transactionList = listSpec{
+	sorts: map[string]sortField{
+		"date":       {column: "transaction_date", field: "TransactionDate", kind: "time"},
+		"amount":     {column: "amount", field: "Amount", kind: "number"},
+		"title":      {column: "title", field: "Title", kind: "text"},
+		"created_at": {column: "created_at", field: "CreatedAt", kind: "time"},
+	},
+	defaultSort: "-date",
+}
+
+// transactionSearchList sorts search results. It adds relevance, the
+// default, to the plain list's sort fields.
+var transactionSearchList = listSpec{
+	sorts: map[string]sortField{
+		"relevance":  {column: "rank", field: "Rank", kind: "number"},
+		"date":       {column: "transaction_date", field: "TransactionDate", kind: "time"},
+		"amount":     {column: "amount", field: "Amount", kind: "number"},
+		"title":      {column: "title", field: "Title", kind: "text"},
+		"created_at": {column: "created_at", field: "CreatedAt", kind: "time"},
+	},
+	defaultSort: "-relevance",
+}
+
+// transactionMatch is a search hit: the transaction, its rank and a
+// snippet with the matching words wrapped in <mark> tags.
+type transactionMatch struct {
+	models.Transaction
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
 }
 
 func (h *Handler) ListTransactions(c *gin.Context) {
@@ -26,28 +65,90 @@ func (h *Handler) ListTransactions(c *gin.Context) {
 		return
 	}
 
-	query := h.DB.Where("user_id = ?", userID)
+	query, err := transactionFilters(c, h.DB.Model(&models.Transaction{}).Where("user_id = ?", userID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if q := c.Query("q"); q != "" {
+		tsquery := search.Query(q)
+		if tsquery == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid q"})
+			return
+		}
+		listPage[transactionMatch](c, searchTransactions(h.DB, query, tsquery), transactionSearchList)
+		return
+	}
+
+	listPage[models.Transaction](c, query, transactionList)
+}
+
+// searchTransactions narrows query to rows matching tsquery and wraps it so
+// the page machinery can sort and seek on the computed rank.
+func searchTransactions(db, query *gorm.DB, tsquery string) *gorm.DB {
+	const q = "to_tsquery('" + search.Config + "', ?)"
+	inner := query.
+		Select("transactions.*, "+
+			"ts_rank_cd(search_vector, "+q+", 32)::float8 AS rank, "+
+			"ts_headline('"+search.Config+"', concat_ws(' · ', title, nullif(merchant, ''), nullif(notes, '')), "+q+
+			", 'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=8, MaxFragments=2') AS snippet",
+			tsquery, tsquery).
+		Where("search_vector @@ "+q, tsquery)
+	return db.Table("(?) AS t", inner)
+}
+
+// transactionFilters applies the list filters: type, category, status,
+// account_id, from/to (inclusive dates) and min_amount/max_amount.
+func transactionFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
 	if t := c.Query("type"); t != "" {
 		query = query.Where("type = ?", t)
 	}
 	if cat := c.Query("category"); cat != "" {
 		query = query.Where("category = ?", cat)
 	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
 
-	limit := 50
-	if l := c.Query("limit"); l != "" {
-		if v, err := strconv.Atoi(l); err == nil {
-			limit = v
-		}
+	accountID, err := queryUUID(c, "account_id")
+	if err != nil {
+		return nil, err
+	}
+	if accountID != nil {
+		query = query.Where("account_id = ?", *accountID)
 	}
 
-	var txs []models.Transaction
-	if err := query.Order("transaction_date desc").Limit(limit).Find(&txs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
-		return
+	from, err := queryDate(c, "from")
+	if err != nil {
+		return nil, err
+	}
+	if from != nil {
+		query = query.Where("transaction_date >= ?", *from)
+	}
+	to, err := queryDate(c, "to")
+	if err != nil {
+		return nil, err
+	}
+	if to != nil {
+		query = query.Where("transaction_date < ?", to.AddDate(0, 0, 1))
 	}
 
-	c.JSON(http.StatusOK, txs)
+	minAmount, err := queryFloat(c, "min_amount")
+	if err != nil {
+		return nil, err
+	}
+	if minAmount != nil {
+		query = query.Where("amount >= ?", *minAmount)
+	}
+	maxAmount, err := queryFloat(c, "max_amount")
+	if err != nil {
+		return nil, err
+	}
+	if maxAmount != nil {
+		query = query.Where("amount <= ?", *maxAmount)
+	}
+	return query, nil
 }
 
 func (h *Handler) CreateTransaction(c *gin.Context) {
@@ -68,6 +169,10 @@ func (h *Handler) CreateTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
 		return
 	}
+	if !validEntryStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending or cleared"})
+		return
+	}
 
 	tx := models.Transaction{
 		UserID:          userID,
@@ -77,14 +182,23 @@ func (h *Handler) CreateTransaction(c *gin.Context) {
 		Type:            req.Type,
 		Category:        req.Category,
 		TransactionDate: date,
+		Merchant:        req.Merchant,
+		Tags:            req.Tags,
+		IsTransfer:      req.IsTransfer,
+		Attachments:     req.Attachments,
+		Status:          req.Status,
 	}
 
-	if err := h.DB.Create(&tx).Error; err != nil {
+	txs := []models.Transaction{tx}
+	if err := h.DB.Transaction(func(db *gorm.DB) error {
+		return h.createTransactions(db, txs)
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
+	h.checkBudgetAlerts(userID, txs)
 
-	c.JSON(http.StatusCreated, tx)
+	c.JSON(http.StatusCreated, txs[0])
 }
 
 func (h *Handler) GetTransaction(c *gin.Context) {
@@ -133,22 +247,66 @@ func (h *Handler) UpdateTransaction(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"})
 		return
 	}
+	if !validEntryStatus(req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending or cleared"})
+		return
+	}
 
-	updates := map[string]interface{}{
-		"account_id":       req.AccountID,
-		"title":            req.Title,
-		"amount":           req.Amount,
-		"type":             req.Type,
-		"category":         req.Category,
-		"transaction_date": date,
+	locked, err := transactionLocked(h.DB, userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if locked {
+		c.JSON(http.StatusConflict, gin.H{"error": errTransactionLocked.Error()})
+		return
 	}
 
-	if err := h.DB.Model(&models.Transaction{}).
-		Where("id = ? AND user_id = ?", id, userID).
-		Updates(updates).Error; err != nil {
+	updates := models.Transaction{
+		AccountID:       req.AccountID,
+		Title:           req.Title,
+		Amount:          req.Amount,
+		Type:            req.Type,
+		Category:        req.Category,
+		TransactionDate: date,
+		Merchant:        req.Merchant,
+		Tags:            req.Tags,
+		IsTransfer:      req.IsTransfer,
+		Attachments:     req.Attachments,
+		Status:          req.Status,
+	}
+	columns := []string{"account_id", "title", "amount", "type", "category", "transaction_date",
+		"merchant", "tags", "is_transfer", "attachments"}
+	if req.Status != "" {
+		columns = append(columns, "status")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := lockTransaction(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		detached, err := followImportBatch(tx, before, &updates)
+		if err != nil {
+			return err
+		}
+		cols := columns
+		if detached {
+			cols = append(cols, "import_batch_id")
+		}
+		// Tags go through the JSON serializer, which only runs for struct
+		// updates.
+		return tx.Model(&before).Select(cols).Updates(&updates).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
+	h.checkBudgetAlerts(userID, []models.Transaction{updates})
 
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
 }
@@ -166,10 +324,111 @@ func (h *Handler) DeleteTransaction(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Transaction{}).Error; err != nil {
+	locked, err := transactionLocked(h.DB, userID, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if locked {
+		c.JSON(http.StatusConflict, gin.H{"error": errTransactionLocked.Error()})
+		return
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		before, err := lockTransaction(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if _, err := followImportBatch(tx, before, nil); err != nil {
+			return err
+		}
+		return tx.Delete(&before).Error
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
 }
+
+// createTransactions is the single write path for new transactions, shared by
+// manual entry and statement imports. It runs the owner's categorization
+// rules before saving and sets aside round-ups after. All transactions must
+// belong to the same user.
+func (h *Handler) createTransactions(db *gorm.DB, txs []models.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+
+	engine, err := h.loadRules(db, txs[0].UserID)
+	if err != nil {
+		return err
+	}
+	for i := range txs {
+		engine.Apply(&txs[i], false)
+	}
+
+	if err := db.CreateInBatches(&txs, 500).Error; err != nil {
+		return err
+	}
+	return recordRoundUps(db, txs)
+}
+
+// lockTransaction loads one of the user's transactions that is not
+// reconciled, locked until tx ends.
+func lockTransaction(tx *gorm.DB, userID, id uuid.UUID) (models.Transaction, error) {
+	var t models.Transaction
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ? AND status <> ?", id, userID, "reconciled").
+		First(&t).Error
+	return t, err
+}
+
+// followImportBatch keeps an imported transaction's batch and account
+// balance in step when the transaction changes to after, or is deleted
+// when after is nil, so undoing the batch reverses exactly what is left of
+// it. A transaction moved to another account leaves its batch, becoming a
+// manual entry there, and detached reports that.
+func followImportBatch(db *gorm.DB, before models.Transaction, after *models.Transaction) (detached bool, err error) {
+	if before.ImportBatchID == nil || before.AccountID == nil {
+		return false, nil
+	}
+	batchID, accountID := *before.ImportBatchID, *before.AccountID
+	if after == nil {
+		return false, moveBatchAmount(db, batchID, accountID, -signedAmount(before), -1)
+	}
+	if after.AccountID == nil || *after.AccountID != accountID {
+		return true, moveBatchAmount(db, batchID, accountID, -signedAmount(before), -1)
+	}
+	return false, moveBatchAmount(db, batchID, accountID, roundCents(signedAmount(*after)-signedAmount(before)), 0)
+}
+
+// adjustBalance adds delta to an account's balance without reading it first,
+// so concurrent adjustments cannot overwrite each other.
+func adjustBalance(db *gorm.DB, accountID uuid.UUID, delta float64) error {
+	if delta == 0 {
+		return nil
+	}
+	return db.Model(&models.Account{}).
+		Where("id = ?", accountID).
+		Update("balance", gorm.Expr("balance + ?", delta)).Error
+}
+
+// signedAmount is the transaction's effect on its account balance.
+func signedAmount(t models.Transaction) float64 {
+	if t.Type == "expense" {
+		return -t.Amount
+	}
+	return t.Amount
+}
+
+// validEntryStatus reports whether a client may set status to s. Only a
+// completed reconciliation marks transactions reconciled.
+func validEntryStatus(s string) bool {
+	return s == "" || s == "pending" || s == "cleared"
+}
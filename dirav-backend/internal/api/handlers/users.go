@@ -2,14 +2,20 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
+	"dirav-backend/internal/analytics"
 	"dirav-backend/internal/models"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type updateUserRequest struct {
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
+	FirstName        string   `json:"first_name"`
+	LastName         string   `json:"last_name"`
+	MonthlyAllowance *float64 `json:"monthly_allowance"`
+	Timezone         string   `json:"timezone"`
 }
 
 func (h *Handler) GetMe(c *gin.Context) {
@@ -26,10 +32,13 @@ func (h *Handler) GetMe(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":         user.ID.String(),
-		"email":      user.Email,
-		"first_name": user.FirstName,
-		"last_name":  user.LastName,
+		"id":                user.ID.String(),
+		"email":             user.Email,
+		"first_name":        user.FirstName,
+		"last_name":         user.LastName,
+		"monthly_allowance": user.MonthlyAllowance,
+		"timezone":          user.Timezone,
+		"is_admin":          user.IsAdmin,
 	})
 }
 
@@ -53,6 +62,20 @@ func (h *Handler) UpdateMe(c *gin.Context) {
 	if req.LastName != "" {
 		updates["last_name"] = req.LastName
 	}
+	if req.MonthlyAllowance != nil {
+		if *req.MonthlyAllowance < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "monthly_allowance must not be negative"})
+			return
+		}
+		updates["monthly_allowance"] = *req.MonthlyAllowance
+	}
+	if req.Timezone != "" {
+		if _, err := analytics.LoadLocation(req.Timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["timezone"] = req.Timezone
+	}
 
 	if len(updates) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "no updates"})
@@ -66,3 +89,27 @@ func (h *Handler) UpdateMe(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"status": "updated"})
 }
+
+// userLocation is the user's timezone. A zone this server no longer knows
+// falls back to UTC rather than failing every request.
+func userLocation(db *gorm.DB, userID uuid.UUID) (*time.Location, error) {
+	var user models.User
+	if err := db.Select("timezone").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	loc, err := analytics.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
+
+// userNow is the current time in the user's timezone, so the dates taken
+// from it are the user's days.
+func (h *Handler) userNow(userID uuid.UUID) (time.Time, error) {
+	loc, err := userLocation(h.DB, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return h.forecaster().Clock.Now().In(loc), nil
+}
@@ -24,25 +24,107 @@ func Register(r *gin.Engine, h *handlers.Handler) {
 	authed.GET("/accounts/:id", h.GetAccount)
 	authed.PUT("/accounts/:id", h.UpdateAccount)
 	authed.DELETE("/accounts/:id", h.DeleteAccount)
+	authed.GET("/accounts/:id/reconciliations", h.ListReconciliations)
+	authed.POST("/accounts/:id/reconciliations", h.StartReconciliation)
+
+	authed.GET("/reconciliations/:id", h.GetReconciliation)
+	authed.POST("/reconciliations/:id/clear", h.ClearTransactions)
+	authed.POST("/reconciliations/:id/finish", h.FinishReconciliation)
+	authed.DELETE("/reconciliations/:id", h.CancelReconciliation)
 
 	authed.GET("/transactions", h.ListTransactions)
 	authed.POST("/transactions", h.CreateTransaction)
+	authed.GET("/transactions/duplicates", h.ListDuplicates)
+	authed.POST("/transactions/duplicates/dismiss", h.DismissDuplicate)
 	authed.GET("/transactions/:id", h.GetTransaction)
 	authed.PUT("/transactions/:id", h.UpdateTransaction)
 	authed.DELETE("/transactions/:id", h.DeleteTransaction)
+	authed.POST("/transactions/:id/merge", h.MergeTransactions)
+	authed.POST("/transactions/:id/unlock", h.UnlockTransaction)
+
+	authed.GET("/imports", h.ListImports)
+	authed.POST("/imports/csv/preview", h.PreviewCSVImport)
+	authed.POST("/imports/csv", h.CommitCSVImport)
+	authed.POST("/imports/ofx/preview", h.PreviewOFXImport)
+	authed.POST("/imports/ofx", h.CommitOFXImport)
+	authed.POST("/imports/qif/preview", h.PreviewQIFImport)
+	authed.POST("/imports/qif", h.CommitQIFImport)
+	authed.POST("/imports/camt053/preview", h.PreviewCAMTImport)
+	authed.POST("/imports/camt053", h.CommitCAMTImport)
+	authed.POST("/imports/mt940/preview", h.PreviewMT940Import)
+	authed.POST("/imports/mt940", h.CommitMT940Import)
+	authed.POST("/imports/:id/undo", h.UndoImport)
+	authed.GET("/imports/profiles", h.ListImportProfiles)
+	authed.POST("/imports/profiles", h.CreateImportProfile)
+	authed.PUT("/imports/profiles/:id", h.UpdateImportProfile)
+	authed.DELETE("/imports/profiles/:id", h.DeleteImportProfile)
+
+	authed.GET("/rules", h.ListRules)
+	authed.POST("/rules", h.CreateRule)
+	authed.PUT("/rules/:id", h.UpdateRule)
+	authed.DELETE("/rules/:id", h.DeleteRule)
+	authed.POST("/rules/test", h.TestRule)
+	authed.POST("/rules/apply", h.ApplyRules)
+	authed.GET("/rules/runs/:id", h.GetRuleRun)
 
 	authed.GET("/budgets", h.ListBudgets)
 	authed.POST("/budgets", h.CreateBudget)
+	authed.GET("/budgets/report", h.BudgetReport)
+	authed.GET("/budgets/forecast", h.BudgetForecast)
+	authed.GET("/budgets/templates", h.ListBudgetTemplates)
+	authed.POST("/budgets/from-template", h.CreateBudgetsFromTemplate)
+	authed.GET("/budgets/alerts", h.ListBudgetAlerts)
+	authed.POST("/budgets/alerts/:id/read", h.MarkBudgetAlertRead)
+	authed.GET("/budgets/moves", h.ListBudgetMoves)
+	authed.POST("/budgets/moves", h.MoveBudgetMoney)
 	authed.GET("/budgets/:id", h.GetBudget)
 	authed.PUT("/budgets/:id", h.UpdateBudget)
 	authed.DELETE("/budgets/:id", h.DeleteBudget)
 	authed.GET("/budgets/:id/progress", h.BudgetProgress)
+	authed.GET("/budgets/:id/periods", h.ListBudgetPeriods)
+
+	authed.GET("/plans/:month", h.GetPlan)
+	authed.PUT("/plans/:month", h.SavePlan)
+	authed.DELETE("/plans/:month", h.DeletePlan)
+	authed.POST("/plans/:month/copy", h.CopyPlan)
 
 	authed.GET("/savings", h.ListSavings)
 	authed.POST("/savings", h.CreateSavings)
+	authed.GET("/savings/round-ups", h.GetRoundUpSettings)
+	authed.PUT("/savings/round-ups", h.UpdateRoundUpSettings)
+	authed.GET("/savings/round-ups/report", h.RoundUpReport)
+	authed.POST("/savings/allocate", h.AllocateSavings)
+	authed.GET("/savings/invitations", h.ListMyInvitations)
+	authed.POST("/savings/invitations/:id/accept", h.AcceptSavingsInvitation)
+	authed.POST("/savings/invitations/:id/decline", h.DeclineSavingsInvitation)
 	authed.PUT("/savings/:id", h.UpdateSavings)
 	authed.DELETE("/savings/:id", h.DeleteSavings)
 	authed.POST("/savings/:id/contribute", h.ContributeSavings)
+	authed.POST("/savings/:id/withdraw", h.WithdrawSavings)
+	authed.GET("/savings/:id/projection", h.GetSavingsProjection)
+	authed.GET("/savings/:id/contributions", h.ListSavingsContributions)
+	authed.GET("/savings/:id/schedules", h.ListContributionSchedules)
+	authed.POST("/savings/:id/schedules", h.CreateContributionSchedule)
+	authed.DELETE("/savings/schedules/:id", h.DeleteContributionSchedule)
+	authed.POST("/savings/schedules/:id/pause", h.PauseContributionSchedule)
+	authed.POST("/savings/schedules/:id/resume", h.ResumeContributionSchedule)
+	authed.GET("/savings/schedules/:id/runs", h.ListContributionRuns)
+	authed.GET("/savings/:id/members", h.ListSavingsMembers)
+	authed.PUT("/savings/:id/members/:user_id", h.UpdateSavingsMember)
+	authed.DELETE("/savings/:id/members/:user_id", h.RemoveSavingsMember)
+	authed.GET("/savings/:id/invitations", h.ListSavingsInvitations)
+	authed.POST("/savings/:id/invitations", h.CreateSavingsInvitation)
+	authed.DELETE("/savings/:id/invitations/:invitation_id", h.RevokeSavingsInvitation)
 
 	authed.GET("/analytics/summary", h.Summary)
+	authed.GET("/analytics/categories", h.CategoryBreakdown)
+
+	authed.GET("/export", h.Export)
+
+	admin := authed.Group("/admin")
+	admin.Use(h.RequireAdmin)
+	admin.GET("/budget-templates", h.AdminListBudgetTemplates)
+	admin.POST("/budget-templates", h.AdminCreateBudgetTemplate)
+	admin.PUT("/budget-templates/:slug", h.AdminUpdateBudgetTemplate)
+	admin.DELETE("/budget-templates/:slug", h.AdminDeleteBudgetTemplate)
 }
@@ -0,0 +1,66 @@
+package budgeting
+
+import (
+	"errors"
+	"sort"
+)
+
+// DefaultThresholds are the percentages of a budget that raise an alert
+// when a budget does not set its own.
+var DefaultThresholds = []int{50, 80, 100}
+
+// Alert kinds. A threshold of 100% or more means the budget is used up or
+// overspent.
+const (
+	AlertThreshold  = "threshold"
+	AlertOverBudget = "over_budget"
+)
+
+// maxThreshold caps thresholds at ten times the budget.
+const maxThreshold = 1000
+
+// NormalizeThresholds validates alert thresholds and returns them sorted
+// without duplicates. An empty list is valid and turns alerts off.
+func NormalizeThresholds(ts []int) ([]int, error) {
+	out := make([]int, 0, len(ts))
+	seen := map[int]bool{}
+	for _, t := range ts {
+		if t < 1 || t > maxThreshold {
+			return nil, errors.New("alert thresholds must be between 1 and 1000")
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// Thresholds returns the budget thresholds to check: ts, or the defaults
+// when ts is nil.
+func Thresholds(ts []int) []int {
+	if ts == nil {
+		return DefaultThresholds
+	}
+	return ts
+}
+
+// Reached returns the thresholds that percentUsed has met or passed.
+func Reached(thresholds []int, percentUsed float64) []int {
+	var out []int
+	for _, t := range thresholds {
+		if percentUsed >= float64(t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// AlertKind names the alert raised at threshold.
+func AlertKind(threshold int) string {
+	if threshold >= 100 {
+		return AlertOverBudget
+	}
+	return AlertThreshold
+}
@@ -0,0 +1,57 @@
+package budgeting
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeThresholds(t *testing.T) {
+	got, err := NormalizeThresholds([]int{100, 50, 80, 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []int{50, 80, 100}) {
+		t.Errorf("got %v", got)
+	}
+
+	if got, err := NormalizeThresholds([]int{}); err != nil || len(got) != 0 {
+		t.Errorf("empty list: %v, %v", got, err)
+	}
+	for _, bad := range [][]int{{0}, {-5}, {1001}} {
+		if _, err := NormalizeThresholds(bad); err == nil {
+			t.Errorf("NormalizeThresholds(%v) accepted", bad)
+		}
+	}
+}
+
+func TestThresholds(t *testing.T) {
+	if got := Thresholds(nil); !reflect.DeepEqual(got, DefaultThresholds) {
+		t.Errorf("nil thresholds = %v, want defaults", got)
+	}
+	if got := Thresholds([]int{}); len(got) != 0 {
+		t.Errorf("empty thresholds = %v, want none", got)
+	}
+}
+
+func TestReached(t *testing.T) {
+	cases := []struct {
+		percent float64
+		want    []int
+	}{
+		{49.99, nil},
+		{50, []int{50}},
+		{99.5, []int{50, 80}},
+		{130, []int{50, 80, 100}},
+	}
+	for _, c := range cases {
+		if got := Reached(DefaultThresholds, c.percent); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Reached(%v) = %v, want %v", c.percent, got, c.want)
+		}
+	}
+}
+
+func TestAlertKind(t *testing.T) {
+	if AlertKind(80) != AlertThreshold || AlertKind(100) != AlertOverBudget || AlertKind(120) != AlertOverBudget {
+		t.Error("unexpected alert kinds")
+	}
+}
@@ -0,0 +1,152 @@
+// Package budgeting computes budget periods and progress. Periods repeat from
+// the budget's start date: a monthly budget starting on the 15th runs from
+// the 15th to the 14th of the next month.
+package budgeting
+
+import (
+	"math"
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+// Periods a budget can repeat over.
+const (
+	Daily   = "daily"
+	Weekly  = "weekly"
+	Monthly = "monthly"
+	Yearly  = "yearly"
+)
+
+// ValidPeriod reports whether p is a supported budget period.
+func ValidPeriod(p string) bool {
+	switch p {
+	case Daily, Weekly, Monthly, Yearly:
+		return true
+	}
+	return false
+}
+
+// Window is one budget period, from Start up to but not including End.
+type Window struct {
+	Index int
+	Start time.Time
+	End   time.Time
+}
+
+// Days is the length of the window in calendar days.
+func (w Window) Days() int {
+	return daysBetween(w.Start, w.End)
+}
+
+// Last is the final calendar day of the window.
+func (w Window) Last() time.Time {
+	return w.End.AddDate(0, 0, -1)
+}
+
+// Contains reports whether t falls inside the window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// Nth returns the budget's period number n, counting from 0 at the start
+// date. A budget's end date cuts its last period short.
+func Nth(b models.Budget, n int) Window {
+	start := dateOf(b.StartDate)
+	w := Window{
+		Index: n,
+		Start: advance(start, b.Period, n),
+		End:   advance(start, b.Period, n+1),
+	}
+	if b.EndDate != nil {
+		if end := dateOf(*b.EndDate).AddDate(0, 0, 1); w.End.After(end) {
+			w.End = end
+		}
+	}
+	return w
+}
+
+// At returns the budget period that contains the calendar day of t, taken
+// in t's own location so a user's local time falls in their local day.
+// Before the start date that is the first period, after the end date the
+// last one.
+func At(b models.Budget, t time.Time) Window {
+	start := dateOf(b.StartDate)
+	t = dateOf(t)
+	if b.EndDate != nil && t.After(dateOf(*b.EndDate)) {
+		t = dateOf(*b.EndDate)
+	}
+	if t.Before(start) {
+		return Nth(b, 0)
+	}
+
+	n := estimate(start, b.Period, t)
+	for n > 0 && advance(start, b.Period, n).After(t) {
+		n--
+	}
+	for !advance(start, b.Period, n+1).After(t) {
+		n++
+	}
+	return Nth(b, n)
+}
+
+// Between lists the budget's periods that overlap [from, to).
+func Between(b models.Budget, from, to time.Time) []Window {
+	var out []Window
+	for w := At(b, from); w.Start.Before(to); w = Nth(b, w.Index+1) {
+		if w.End.After(from) {
+			out = append(out, w)
+		}
+		if b.EndDate != nil && !w.End.Before(dateOf(*b.EndDate).AddDate(0, 0, 1)) {
+			break
+		}
+	}
+	return out
+}
+
+func estimate(start time.Time, period string, t time.Time) int {
+	switch period {
+	case Daily:
+		return daysBetween(start, t)
+	case Weekly:
+		return daysBetween(start, t) / 7
+	case Yearly:
+		return t.Year() - start.Year()
+	default:
+		return (t.Year()-start.Year())*12 + int(t.Month()) - int(start.Month())
+	}
+}
+
+// advance moves start forward by n periods. Months keep the start's day,
+// clamped to the end of shorter months, so a budget starting on the 31st
+// runs 31 Jan, 28 Feb, 31 Mar. Unknown periods are treated as monthly.
+func advance(start time.Time, period string, n int) time.Time {
+	switch period {
+	case Daily:
+		return start.AddDate(0, 0, n)
+	case Weekly:
+		return start.AddDate(0, 0, 7*n)
+	case Yearly:
+		return addMonths(start, 12*n)
+	default:
+		return addMonths(start, n)
+	}
+}
+
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(math.Round(dateOf(b).Sub(dateOf(a)).Hours() / 24))
+}
@@ -0,0 +1,115 @@
+package budgeting
+
+import (
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestAt(t *testing.T) {
+	end := day("2024-06-10")
+	cases := []struct {
+		name       string
+		budget     models.Budget
+		at         string
+		start, end string
+	}{
+		{"monthly mid-month start", models.Budget{Period: Monthly, StartDate: day("2024-01-15")}, "2024-03-20", "2024-03-15", "2024-04-14"},
+		{"monthly on boundary", models.Budget{Period: Monthly, StartDate: day("2024-01-15")}, "2024-03-15", "2024-03-15", "2024-04-14"},
+		{"monthly day before boundary", models.Budget{Period: Monthly, StartDate: day("2024-01-15")}, "2024-03-14", "2024-02-15", "2024-03-14"},
+		{"monthly clamps to month end", models.Budget{Period: Monthly, StartDate: day("2024-01-31")}, "2024-02-29", "2024-02-29", "2024-03-30"},
+		{"weekly", models.Budget{Period: Weekly, StartDate: day("2024-01-01")}, "2024-01-17", "2024-01-15", "2024-01-21"},
+		{"daily", models.Budget{Period: Daily, StartDate: day("2024-01-01")}, "2024-02-03", "2024-02-03", "2024-02-03"},
+		{"yearly", models.Budget{Period: Yearly, StartDate: day("2023-04-01")}, "2024-03-31", "2023-04-01", "2024-03-31"},
+		{"before start", models.Budget{Period: Monthly, StartDate: day("2024-05-01")}, "2024-01-01", "2024-05-01", "2024-05-31"},
+		{"cut by end date", models.Budget{Period: Monthly, StartDate: day("2024-01-01"), EndDate: &end}, "2024-06-05", "2024-06-01", "2024-06-10"},
+		{"after end date", models.Budget{Period: Monthly, StartDate: day("2024-01-01"), EndDate: &end}, "2024-09-01", "2024-06-01", "2024-06-10"},
+	}
+	for _, c := range cases {
+		w := At(c.budget, day(c.at))
+		if got := w.Start.Format("2006-01-02"); got != c.start {
+			t.Errorf("%s: start = %s, want %s", c.name, got, c.start)
+		}
+		if got := w.Last().Format("2006-01-02"); got != c.end {
+			t.Errorf("%s: end = %s, want %s", c.name, got, c.end)
+		}
+	}
+}
+
+func TestAtLocalTime(t *testing.T) {
+	b := models.Budget{Period: Monthly, StartDate: day("2025-01-01")}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 01:00 on 1 November in Tokyo is still 31 October in UTC, but the
+	// user is in November's period.
+	if w := At(b, time.Date(2025, 11, 1, 1, 0, 0, 0, tokyo)); w.Start.Format("2006-01-02") != "2025-11-01" {
+		t.Errorf("Tokyo: start = %s, want 2025-11-01", w.Start.Format("2006-01-02"))
+	}
+	// 22:00 on 31 October in New York is already November in UTC.
+	if w := At(b, time.Date(2025, 10, 31, 22, 0, 0, 0, newYork)); w.Start.Format("2006-01-02") != "2025-10-01" {
+		t.Errorf("New York: start = %s, want 2025-10-01", w.Start.Format("2006-01-02"))
+	}
+}
+
+func TestBetween(t *testing.T) {
+	b := models.Budget{Period: Monthly, StartDate: day("2024-01-01")}
+	ws := Between(b, day("2024-02-10"), day("2024-05-01"))
+	if len(ws) != 3 {
+		t.Fatalf("got %d windows, want 3", len(ws))
+	}
+	if ws[0].Index != 1 || ws[2].Start != day("2024-04-01") {
+		t.Errorf("unexpected windows %+v", ws)
+	}
+
+	end := day("2024-02-15")
+	b.EndDate = &end
+	if ws := Between(b, day("2024-01-01"), day("2025-01-01")); len(ws) != 2 {
+		t.Errorf("got %d windows past the end date, want 2", len(ws))
+	}
+}
+
+func TestNewProgress(t *testing.T) {
+	b := models.Budget{Period: Monthly, StartDate: day("2024-04-01")}
+	w := At(b, day("2024-04-10"))
+
+	p := NewProgress(b.ID, 300, 150, w, day("2024-04-10"))
+	if p.PercentUsed != 50 || p.Remaining != 150 {
+		t.Errorf("percent/remaining = %v/%v", p.PercentUsed, p.Remaining)
+	}
+	if p.DaysElapsed != 10 || p.DaysRemaining != 20 {
+		t.Errorf("days = %d/%d, want 10/20", p.DaysElapsed, p.DaysRemaining)
+	}
+	if p.DailyPace != 15 {
+		t.Errorf("daily pace = %v, want 15", p.DailyPace)
+	}
+	// 150 left over the 21 days from the 10th to the 30th.
+	if p.AllowedDailyPace != 7.14 {
+		t.Errorf("allowed pace = %v, want 7.14", p.AllowedDailyPace)
+	}
+
+	over := NewProgress(b.ID, 100, 120, w, day("2024-04-10"))
+	if over.AllowedDailyPace != 0 || over.PercentUsed != 120 {
+		t.Errorf("over budget: %+v", over)
+	}
+
+	done := NewProgress(b.ID, 300, 150, w, day("2024-05-03"))
+	if done.DaysElapsed != 30 || done.DaysRemaining != 0 || done.AllowedDailyPace != 0 {
+		t.Errorf("finished period: %+v", done)
+	}
+}
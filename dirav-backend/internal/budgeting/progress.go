@@ -0,0 +1,77 @@
+package budgeting
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Progress is a budget's state within one period.
+type Progress struct {
+	BudgetID uuid.UUID `json:"budget_id"`
+	// Amount is what the period holds to spend: Budgeted plus CarriedOver
+	// and Moved.
+	Amount        float64 `json:"amount"`
+	Budgeted      float64 `json:"budgeted"`
+	CarriedOver   float64 `json:"carried_over"`
+	Moved         float64 `json:"moved"`
+	Spent         float64 `json:"spent"`
+	Remaining     float64 `json:"remaining"`
+	PercentUsed   float64 `json:"percent_used"`
+	PeriodStart   string  `json:"period_start"`
+	PeriodEnd     string  `json:"period_end"`
+	DaysElapsed   int     `json:"days_elapsed"`
+	DaysRemaining int     `json:"days_remaining"`
+	// DailyPace is what was spent per elapsed day so far.
+	DailyPace float64 `json:"daily_pace"`
+	// AllowedDailyPace is what can still be spent per remaining day without
+	// going over; 0 once the budget is used up or the period is over.
+	AllowedDailyPace float64 `json:"allowed_daily_pace"`
+}
+
+// NewProgress reports amount against spent for window w as of today. Days
+// are counted inclusively: on the first day of a period one day has
+// elapsed.
+func NewProgress(id uuid.UUID, amount, spent float64, w Window, today time.Time) Progress {
+	p := Progress{
+		BudgetID:    id,
+		Amount:      round(amount),
+		Budgeted:    round(amount),
+		Spent:       round(spent),
+		Remaining:   round(amount - spent),
+		PeriodStart: w.Start.Format("2006-01-02"),
+		PeriodEnd:   w.Last().Format("2006-01-02"),
+	}
+	if amount > 0 {
+		p.PercentUsed = round(spent / amount * 100)
+	}
+
+	elapsed := daysBetween(w.Start, today) + 1
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	if elapsed > w.Days() {
+		elapsed = w.Days()
+	}
+	p.DaysElapsed = elapsed
+	p.DaysRemaining = w.Days() - elapsed
+
+	if elapsed > 0 {
+		p.DailyPace = round(spent / float64(elapsed))
+	}
+	// Today still counts as spendable, so the allowance spreads over it and
+	// the days after.
+	left := daysBetween(today, w.End)
+	if left > w.Days() {
+		left = w.Days()
+	}
+	if p.Remaining > 0 && left > 0 {
+		p.AllowedDailyPace = round(p.Remaining / float64(left))
+	}
+	return p
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
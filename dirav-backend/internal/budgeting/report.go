@@ -0,0 +1,97 @@
+package budgeting
+
+import (
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+// PeriodReport is what was planned against what was spent in one budget
+// period. Variance is what was left: positive under budget, negative over.
+type PeriodReport struct {
+	PeriodStart string  `json:"period_start"`
+	PeriodEnd   string  `json:"period_end"`
+	Planned     float64 `json:"planned"`
+	CarriedOver float64 `json:"carried_over"`
+	Moved       float64 `json:"moved"`
+	Actual      float64 `json:"actual"`
+	Variance    float64 `json:"variance"`
+	PercentUsed float64 `json:"percent_used"`
+	OverBudget  bool    `json:"over_budget"`
+}
+
+// DateKey is the map key History uses for a period start.
+func DateKey(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// History reports a budget's windows, oldest first, from the first that
+// ends after from. windows must be consecutive and start early enough for
+// carry-over to be worked out, normally at the budget's first period.
+// actual holds each window's spending and snapshots the stored periods,
+// both keyed by DateKey of the window start.
+//
+// Stored periods are planned at their snapshot's budget, with the money
+// moved in or out of them; the others at the budget's amount. Carry-over
+// follows actual spending under RolloverOf, as Snapshots works it out.
+func History(b models.Budget, windows []Window, snapshots map[string]models.BudgetPeriod, actual map[string]float64, from time.Time) []PeriodReport {
+	var out []PeriodReport
+	var prev float64
+	for i, w := range windows {
+		key := DateKey(w.Start)
+		snap, stored := snapshots[key]
+		spent := round(actual[key])
+
+		r := PeriodReport{
+			PeriodStart: key,
+			PeriodEnd:   w.Last().Format("2006-01-02"),
+			Planned:     b.Amount,
+			Actual:      spent,
+		}
+		if stored {
+			r.Planned = snap.Budgeted
+			r.Moved = snap.Moved
+		}
+		if i > 0 {
+			r.CarriedOver = Carry(RolloverOf(b, snap), prev)
+		}
+
+		funds := r.Planned + r.CarriedOver + r.Moved
+		r.Variance = round(funds - spent)
+		r.OverBudget = r.Variance < 0
+		if funds > 0 {
+			r.PercentUsed = round(spent / funds * 100)
+		}
+
+		prev = r.Variance
+		if w.End.After(from) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Totals sums a budget's reported periods.
+type Totals struct {
+	Planned     float64 `json:"planned"`
+	Actual      float64 `json:"actual"`
+	Variance    float64 `json:"variance"`
+	PeriodsOver int     `json:"periods_over"`
+}
+
+// Total adds up rows. Variance is planned less actual, leaving carry-over
+// and moves out since they shift money between periods and budgets.
+func Total(rows []PeriodReport) Totals {
+	var t Totals
+	for _, r := range rows {
+		t.Planned += r.Planned
+		t.Actual += r.Actual
+		if r.OverBudget {
+			t.PeriodsOver++
+		}
+	}
+	t.Planned = round(t.Planned)
+	t.Actual = round(t.Actual)
+	t.Variance = round(t.Planned - t.Actual)
+	return t
+}
@@ -0,0 +1,69 @@
+package budgeting
+
+import (
+	"testing"
+
+	"dirav-backend/internal/models"
+)
+
+func TestHistory(t *testing.T) {
+	b := models.Budget{Amount: 100, Period: Monthly, Rollover: RolloverFull, StartDate: day("2024-01-01")}
+	windows := Between(b, day("2024-01-01"), day("2024-05-01"))
+	actual := map[string]float64{
+		"2024-01-01": 80,
+		"2024-02-01": 130,
+		"2024-03-01": 50,
+		"2024-04-01": 100,
+	}
+
+	rows := History(b, windows, nil, actual, day("2024-02-15"))
+	if len(rows) != 3 || rows[0].PeriodStart != "2024-02-01" {
+		t.Fatalf("rows = %+v", rows)
+	}
+	// February carries January's 20, overspends by 10; March inherits -10.
+	if rows[0].CarriedOver != 20 || rows[0].Variance != -10 || !rows[0].OverBudget {
+		t.Errorf("february = %+v", rows[0])
+	}
+	if rows[1].CarriedOver != -10 || rows[1].Variance != 40 || rows[1].PercentUsed != 55.56 {
+		t.Errorf("march = %+v", rows[1])
+	}
+	if rows[2].CarriedOver != 40 || rows[2].Variance != 40 {
+		t.Errorf("april = %+v", rows[2])
+	}
+
+	totals := Total(rows)
+	if totals.Planned != 300 || totals.Actual != 280 || totals.Variance != 20 || totals.PeriodsOver != 1 {
+		t.Errorf("totals = %+v", totals)
+	}
+}
+
+func TestHistorySnapshots(t *testing.T) {
+	b := models.Budget{Amount: 100, Period: Monthly, Rollover: RolloverFull, StartDate: day("2024-01-01")}
+	windows := Between(b, day("2024-01-01"), day("2024-04-01"))
+	snapshots := map[string]models.BudgetPeriod{
+		// January was stored closed with 25 moved in, before a back-dated
+		// expense raised its spending from 90 to 95.
+		"2024-01-01": {Budgeted: 100, Moved: 25, Spent: 90, Available: 35, Rollover: RolloverFull, Closed: true},
+		// February closed while the budget carried nothing over.
+		"2024-02-01": {Budgeted: 100, Spent: 150, Available: -50, Rollover: RolloverNone, Closed: true},
+		// March is open with 15 moved out.
+		"2024-03-01": {Budgeted: 120, Moved: -15},
+	}
+	actual := map[string]float64{"2024-01-01": 95, "2024-02-01": 150, "2024-03-01": 60}
+
+	rows := History(b, windows, snapshots, actual, day("2024-01-01"))
+	if len(rows) != 3 {
+		t.Fatalf("rows = %+v", rows)
+	}
+	if rows[0].Actual != 95 || rows[0].Variance != 30 {
+		t.Errorf("january = %+v", rows[0])
+	}
+	// February keeps the mode it closed under and carries nothing.
+	if rows[1].CarriedOver != 0 || rows[1].Variance != -50 {
+		t.Errorf("february = %+v", rows[1])
+	}
+	// March carries February's overspending under the budget's full mode.
+	if rows[2].Planned != 120 || rows[2].CarriedOver != -50 || rows[2].Moved != -15 || rows[2].Variance != -5 || !rows[2].OverBudget {
+		t.Errorf("march = %+v", rows[2])
+	}
+}
@@ -0,0 +1,42 @@
+package budgeting
+
+// Rollover modes: what a budget carries from one period into the next.
+const (
+	// RolloverNone starts every period afresh.
+	RolloverNone = "none"
+	// RolloverPositive carries unspent money forward; overspending is
+	// forgiven.
+	RolloverPositive = "positive"
+	// RolloverFull carries both: leftovers add to the next period and
+	// overspending is taken out of it.
+	RolloverFull = "full"
+)
+
+// ValidRollover reports whether mode is a supported rollover mode.
+func ValidRollover(mode string) bool {
+	switch mode {
+	case RolloverNone, RolloverPositive, RolloverFull:
+		return true
+	}
+	return false
+}
+
+// Carry is what a period that ended with available left over passes on to
+// the next one under mode.
+func Carry(mode string, available float64) float64 {
+	switch mode {
+	case RolloverFull:
+		return round(available)
+	case RolloverPositive:
+		if available > 0 {
+			return round(available)
+		}
+	}
+	return 0
+}
+
+// Available is what a period holds to spend: its budget plus what was
+// carried over and moved in, minus what was spent.
+func Available(budgeted, carried, moved, spent float64) float64 {
+	return round(budgeted + carried + moved - spent)
+}
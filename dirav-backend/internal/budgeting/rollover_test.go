@@ -0,0 +1,30 @@
+package budgeting
+
+import "testing"
+
+func TestCarry(t *testing.T) {
+	cases := []struct {
+		mode      string
+		available float64
+		want      float64
+	}{
+		{RolloverNone, 40, 0},
+		{RolloverNone, -40, 0},
+		{RolloverPositive, 40, 40},
+		{RolloverPositive, -40, 0},
+		{RolloverFull, 40, 40},
+		{RolloverFull, -40, -40},
+		{"", 40, 0},
+	}
+	for _, c := range cases {
+		if got := Carry(c.mode, c.available); got != c.want {
+			t.Errorf("Carry(%q, %v) = %v, want %v", c.mode, c.available, got, c.want)
+		}
+	}
+}
+
+func TestAvailable(t *testing.T) {
+	if got := Available(200, 35.5, -20, 180.25); got != 35.25 {
+		t.Errorf("Available = %v, want 35.25", got)
+	}
+}
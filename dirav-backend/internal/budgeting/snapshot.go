@@ -0,0 +1,71 @@
+package budgeting
+
+import (
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+// Snapshots works out the snapshot of each of a budget's windows, oldest
+// first. windows must be consecutive, and prev is the snapshot of the
+// period before the first of them, nil when that is the budget's first
+// period. stored holds the snapshots saved before and spent each window's
+// spending, both keyed by DateKey of the window start; a window without a
+// snapshot gets a new one. Periods whose window ended by today are closed.
+//
+// What a closed period budgeted and the rollover mode that carried money
+// into it are kept, so later changes to the budget leave history alone.
+// Moves are always kept. Spending and carry-over follow the transactions,
+// so a transaction dated in a closed period still counts there and in
+// what the periods after it inherit.
+func Snapshots(b models.Budget, windows []Window, prev *models.BudgetPeriod, stored map[string]models.BudgetPeriod, spent map[string]float64, today time.Time) []models.BudgetPeriod {
+	today = dateOf(today)
+	out := make([]models.BudgetPeriod, 0, len(windows))
+	for i, w := range windows {
+		key := DateKey(w.Start)
+		p, ok := stored[key]
+		if !ok {
+			p = models.BudgetPeriod{UserID: b.UserID, BudgetID: b.ID, PeriodStart: w.Start}
+		}
+		if !p.Closed {
+			p.Budgeted = b.Amount
+			p.Rollover = b.Rollover
+		}
+		p.PeriodEnd = w.Last()
+		p.CarriedOver = 0
+		if i > 0 {
+			prev = &out[i-1]
+		}
+		if prev != nil {
+			p.CarriedOver = Carry(RolloverOf(b, p), prev.Available)
+		}
+		p.Spent = round(spent[key])
+		p.Available = Available(p.Budgeted, p.CarriedOver, p.Moved, p.Spent)
+		p.Closed = !w.End.After(today)
+		p.Stale = false
+		out = append(out, p)
+	}
+	return out
+}
+
+// RolloverOf is the mode that carries money into a period: the one kept
+// with a closed snapshot, otherwise the budget's.
+func RolloverOf(b models.Budget, p models.BudgetPeriod) string {
+	if p.Closed && p.Rollover != "" {
+		return p.Rollover
+	}
+	return b.Rollover
+}
+
+// SnapshotChanged reports whether a snapshot differs from the stored one
+// in anything Snapshots works out.
+func SnapshotChanged(stored, p models.BudgetPeriod) bool {
+	return !stored.PeriodEnd.Equal(p.PeriodEnd) ||
+		stored.Budgeted != p.Budgeted ||
+		stored.Rollover != p.Rollover ||
+		stored.CarriedOver != p.CarriedOver ||
+		stored.Spent != p.Spent ||
+		stored.Available != p.Available ||
+		stored.Closed != p.Closed ||
+		stored.Stale != p.Stale
+}
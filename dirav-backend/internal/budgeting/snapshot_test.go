@@ -0,0 +1,86 @@
+package budgeting
+
+import (
+	"testing"
+
+	"dirav-backend/internal/models"
+)
+
+func TestSnapshots(t *testing.T) {
+	b := models.Budget{Amount: 200, Period: Monthly, Rollover: RolloverPositive, StartDate: day("2024-01-01")}
+	windows := Between(b, day("2024-01-01"), day("2024-04-01"))
+	stored := map[string]models.BudgetPeriod{
+		// January closed at 100 with a full rollover and 20 left.
+		"2024-01-01": {Budgeted: 100, Spent: 80, Available: 20, Rollover: RolloverFull, Closed: true},
+		// February closed with 10 moved in.
+		"2024-02-01": {Budgeted: 100, CarriedOver: 20, Moved: 10, Spent: 50, Available: 80, Rollover: RolloverFull, Closed: true},
+		"2024-03-01": {Budgeted: 150, Moved: -30, Rollover: RolloverFull},
+	}
+	// An expense back-dated into January took it over budget.
+	spent := map[string]float64{"2024-01-01": 130, "2024-02-01": 50, "2024-03-01": 40}
+
+	got := Snapshots(b, windows, nil, stored, spent, day("2024-03-10"))
+	if len(got) != 3 {
+		t.Fatalf("got %d snapshots, want 3", len(got))
+	}
+	jan, feb, mar := got[0], got[1], got[2]
+	if jan.Budgeted != 100 || jan.Spent != 130 || jan.Available != -30 || !jan.Closed {
+		t.Errorf("january = %+v", jan)
+	}
+	// February still carries under the full mode it closed with.
+	if feb.Budgeted != 100 || feb.CarriedOver != -30 || feb.Moved != 10 || feb.Available != 30 || !SnapshotChanged(stored["2024-02-01"], feb) {
+		t.Errorf("february = %+v", feb)
+	}
+	// The open period takes the budget's current amount and mode.
+	if mar.Budgeted != 200 || mar.Rollover != RolloverPositive || mar.CarriedOver != 30 || mar.Moved != -30 || mar.Available != 160 || mar.Closed {
+		t.Errorf("march = %+v", mar)
+	}
+
+	// Worked out again, nothing changes.
+	again := map[string]models.BudgetPeriod{}
+	for i, p := range got {
+		again[DateKey(windows[i].Start)] = p
+	}
+	for i, p := range Snapshots(b, windows, nil, again, spent, day("2024-03-10")) {
+		if SnapshotChanged(got[i], p) {
+			t.Errorf("period %d changed: %+v", i, p)
+		}
+	}
+
+	// Started from the closed February snapshot, March works out the same.
+	for _, p := range Snapshots(b, windows[2:], &got[1], again, spent, day("2024-03-10")) {
+		if SnapshotChanged(mar, p) {
+			t.Errorf("march from february = %+v", p)
+		}
+	}
+
+	// A stale snapshot is fresh once worked out again.
+	jan.Stale = true
+	if p := Snapshots(b, windows[:1], nil, map[string]models.BudgetPeriod{"2024-01-01": jan}, spent, day("2024-03-10"))[0]; p.Stale || !SnapshotChanged(jan, p) {
+		t.Errorf("stale january = %+v", p)
+	}
+
+	// A new period starts from the budget.
+	got = Snapshots(b, windows[:1], nil, nil, nil, day("2024-01-10"))
+	if p := got[0]; p.Budgeted != 200 || p.Available != 200 || p.Closed || !p.PeriodEnd.Equal(day("2024-01-31")) {
+		t.Errorf("new period = %+v", p)
+	}
+}
+
+func TestRolloverOf(t *testing.T) {
+	b := models.Budget{Rollover: RolloverFull}
+	cases := []struct {
+		p    models.BudgetPeriod
+		want string
+	}{
+		{models.BudgetPeriod{Rollover: RolloverNone, Closed: true}, RolloverNone},
+		{models.BudgetPeriod{Rollover: RolloverNone}, RolloverFull},
+		// Snapshots stored before modes were kept use the budget's.
+		{models.BudgetPeriod{Closed: true}, RolloverFull},
+	}
+	for _, c := range cases {
+		if got := RolloverOf(b, c.p); got != c.want {
+			t.Errorf("RolloverOf(%+v) = %s, want %s", c.p, got, c.want)
+		}
+	}
+}
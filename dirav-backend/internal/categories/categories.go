@@ -0,0 +1,32 @@
+// Package categories handles the category hierarchy. Categories are plain
+// strings; a child names its parent path first, separated by a colon, as in
+// "Food:Groceries". This is the convention of QIF files, so imported
+// subcategories slot in without mapping.
+package categories
+
+import "strings"
+
+// Separator divides the levels of a category path.
+const Separator = ":"
+
+// Contains reports whether category is parent itself or one of its
+// descendants, ignoring case. An empty parent contains every category.
+func Contains(parent, category string) bool {
+	parent = strings.TrimSpace(parent)
+	if parent == "" {
+		return true
+	}
+	category = strings.TrimSpace(category)
+	if strings.EqualFold(category, parent) {
+		return true
+	}
+	prefix := parent + Separator
+	return len(category) > len(prefix) && strings.EqualFold(category[:len(prefix)], prefix)
+}
+
+// Root returns the top-level category of a path: "Food" for
+// "Food:Groceries".
+func Root(category string) string {
+	root, _, _ := strings.Cut(category, Separator)
+	return strings.TrimSpace(root)
+}
@@ -0,0 +1,32 @@
+package categories
+
+import "testing"
+
+func TestContains(t *testing.T) {
+	cases := []struct {
+		parent, category string
+		want             bool
+	}{
+		{"Food", "Food", true},
+		{"Food", "food", true},
+		{"Food", "Food:Groceries", true},
+		{"food", "Food:Eating out:Coffee", true},
+		{"Food:Groceries", "Food", false},
+		{"Food", "Foodbank", false},
+		{"Food", "", false},
+		{"", "Rent", true},
+	}
+	for _, c := range cases {
+		if got := Contains(c.parent, c.category); got != c.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", c.parent, c.category, got, c.want)
+		}
+	}
+}
+
+func TestRoot(t *testing.T) {
+	for in, want := range map[string]string{"Food:Groceries": "Food", "Rent": "Rent", "": ""} {
+		if got := Root(in); got != want {
+			t.Errorf("Root(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -5,9 +5,12 @@ import (
 
 	"dirav-backend/internal/config"
 	"dirav-backend/internal/models"
+	"dirav-backend/internal/search"
+	"dirav-backend/internal/templates"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func Connect(cfg config.Config) (*gorm.DB, error) {
@@ -39,13 +42,165 @@ func Connect(cfg config.Config) (*gorm.DB, error) {
 		&models.Transaction{},
 		&models.Budget{},
 		&models.SavingsGoal{},
+		&models.ImportProfile{},
+		&models.ImportBatch{},
+		&models.Rule{},
+		&models.RuleRun{},
+		&models.DuplicateDismissal{},
+		&models.Reconciliation{},
+		&models.BudgetPeriod{},
+		&models.BudgetMove{},
+		&models.BudgetAlert{},
+		&models.MonthlyPlan{},
+		&models.PlanAllocation{},
+		&models.BudgetTemplate{},
+		&models.SavingsContribution{},
+		&models.ContributionSchedule{},
+		&models.ContributionRun{},
+		&models.RoundUp{},
+		&models.SavingsMember{},
+		&models.SavingsInvitation{},
+		&models.SurplusAllocation{},
 	); err != nil {
 		return nil, err
 	}
 
+	if err := migrateTransactionSearch(db); err != nil {
+		return nil, err
+	}
+
+	if err := migrateBudgetPeriodStaleness(db); err != nil {
+		return nil, err
+	}
+
+	if err := backfillSavingsContributions(db); err != nil {
+		return nil, err
+	}
+
+	if err := seedBudgetTemplates(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
 func enableUUID(db *gorm.DB) error {
 	return db.Exec("CREATE EXTENSION IF NOT EXISTS \"pgcrypto\";").Error
 }
+
+// migrateTransactionSearch adds the generated full-text column behind
+// transaction search and its GIN index. Titles and merchants weigh most,
+// then category and counterparty, then notes. The column is left out of
+// models.Transaction so GORM never writes to it.
+func migrateTransactionSearch(db *gorm.DB) error {
+	stmts := []string{
+		`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (
+				setweight(to_tsvector('` + search.Config + `', coalesce(title, '')), 'A') ||
+				setweight(to_tsvector('` + search.Config + `', coalesce(merchant, '')), 'A') ||
+				setweight(to_tsvector('` + search.Config + `', coalesce(category, '')), 'B') ||
+				setweight(to_tsvector('` + search.Config + `', coalesce(counterparty, '')), 'B') ||
+				setweight(to_tsvector('` + search.Config + `', coalesce(notes, '')), 'C')
+			) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_transactions_search_vector ON transactions USING GIN (search_vector)`,
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateBudgetPeriodStaleness adds the trigger that marks closed budget
+// periods stale when a transaction dated in them is added, changed or
+// removed, so their snapshots are worked out again on the next read while
+// those still fresh are left as stored. Changes that cannot move spending,
+// such as a new status or note, do not count. Snapshots closed before the
+// trigger existed are marked stale once, when it is first added.
+func migrateBudgetPeriodStaleness(db *gorm.DB) error {
+	var installed bool
+	if err := db.Raw(`SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'transactions_budget_periods_stale')`).
+		Scan(&installed).Error; err != nil {
+		return err
+	}
+	if !installed {
+		if err := db.Exec(`UPDATE budget_periods SET stale = true WHERE closed`).Error; err != nil {
+			return err
+		}
+	}
+
+	stmts := []string{
+		`CREATE OR REPLACE FUNCTION mark_budget_periods_stale() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'UPDATE'
+				AND NEW.transaction_date = OLD.transaction_date
+				AND NEW.amount = OLD.amount
+				AND NEW.type = OLD.type
+				AND NEW.category IS NOT DISTINCT FROM OLD.category
+				AND NEW.account_id IS NOT DISTINCT FROM OLD.account_id
+				AND NEW.is_transfer = OLD.is_transfer THEN
+				RETURN NULL;
+			END IF;
+			IF TG_OP <> 'INSERT' THEN
+				UPDATE budget_periods SET stale = true
+				WHERE user_id = OLD.user_id AND closed AND NOT stale
+					AND period_start <= OLD.transaction_date
+					AND period_end + interval '1 day' > OLD.transaction_date;
+			END IF;
+			IF TG_OP <> 'DELETE' THEN
+				UPDATE budget_periods SET stale = true
+				WHERE user_id = NEW.user_id AND closed AND NOT stale
+					AND period_start <= NEW.transaction_date
+					AND period_end + interval '1 day' > NEW.transaction_date;
+			END IF;
+			RETURN NULL;
+		END
+		$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS transactions_budget_periods_stale ON transactions`,
+		`CREATE TRIGGER transactions_budget_periods_stale
+			AFTER INSERT OR UPDATE OR DELETE ON transactions
+			FOR EACH ROW EXECUTE FUNCTION mark_budget_periods_stale()`,
+	}
+	for _, stmt := range stmts {
+		if err := db.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openingContributionNote is the note on contributions backfilled for
+// amounts saved before contributions were recorded.
+const openingContributionNote = "Opening balance"
+
+// backfillSavingsContributions records what goals saved before
+// contributions were recorded as one opening contribution by the goal's
+// owner, dated the day the goal was created, so every goal's contributions
+// add up to its current amount. Goals that already add up are left alone.
+func backfillSavingsContributions(db *gorm.DB) error {
+	return db.Exec(`INSERT INTO savings_contributions (id, user_id, savings_goal_id, amount, "date", note, created_at)
+		SELECT gen_random_uuid(), g.user_id, g.id,
+			ROUND((g.current_amount - COALESCE(SUM(c.amount), 0))::numeric, 2),
+			(g.created_at AT TIME ZONE 'UTC')::date::timestamp AT TIME ZONE 'UTC',
+			?, now()
+		FROM savings_goals g
+		LEFT JOIN savings_contributions c ON c.savings_goal_id = g.id
+		GROUP BY g.id
+		HAVING ROUND((g.current_amount - COALESCE(SUM(c.amount), 0))::numeric, 2) <> 0`,
+		openingContributionNote).Error
+}
+
+// seedBudgetTemplates adds the built-in budget templates that are not in
+// the database yet. Templates already there, including built-ins an admin
+// has edited, are left as they are.
+func seedBudgetTemplates(db *gorm.DB) error {
+	list, err := templates.Builtin()
+	if err != nil {
+		return err
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "slug"}},
+		DoNothing: true,
+	}).Create(&list).Error
+}
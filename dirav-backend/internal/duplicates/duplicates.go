@@ -0,0 +1,120 @@
+// Package duplicates scores pairs of transactions that look like the same
+// real-world payment entered twice, typically once by hand and once by a
+// statement import.
+package duplicates
+
+import (
+	"math"
+	"strings"
+	"unicode"
+
+	"dirav-backend/internal/models"
+)
+
+// Pair is a candidate duplicate.
+type Pair struct {
+	A         models.Transaction `json:"a"`
+	B         models.Transaction `json:"b"`
+	Score     float64            `json:"score"`
+	DaysApart int                `json:"days_apart"`
+	TitleSim  float64            `json:"title_similarity"`
+}
+
+// Score rates how likely a and b are the same payment, from 0 to 1. Pairs on
+// different accounts, with different amounts or types, further apart than
+// windowDays, or carrying two different bank references never match.
+func Score(a, b models.Transaction, windowDays int) (Pair, bool) {
+	if !sameAccount(a, b) || a.Type != b.Type || math.Abs(a.Amount-b.Amount) >= 0.005 {
+		return Pair{}, false
+	}
+	// Two lines with their own bank references are two distinct bookings,
+	// however alike they look.
+	if a.ExternalID != "" && b.ExternalID != "" && a.ExternalID != b.ExternalID {
+		return Pair{}, false
+	}
+
+	days := int(math.Round(math.Abs(a.TransactionDate.Sub(b.TransactionDate).Hours()) / 24))
+	if days > windowDays {
+		return Pair{}, false
+	}
+
+	sim := TitleSimilarity(a.Title, b.Title)
+	dateScore := 1 - float64(days)/float64(windowDays+1)
+	return Pair{
+		A:         a,
+		B:         b,
+		Score:     round2(0.6*sim + 0.4*dateScore),
+		DaysApart: days,
+		TitleSim:  round2(sim),
+	}, true
+}
+
+// TitleSimilarity compares two titles after dropping case, digits and
+// punctuation, so "STARBUCKS #1234 AMSTERDAM" and "Starbucks Amsterdam" are
+// equal. It is the Dice coefficient of the character bigrams, raised to 0.9
+// when one title is contained in the other.
+func TitleSimilarity(a, b string) float64 {
+	na, nb := normalize(a), normalize(b)
+	if na == "" || nb == "" {
+		return 0
+	}
+	if na == nb {
+		return 1
+	}
+
+	sim := dice(na, nb)
+	if strings.Contains(na, nb) || strings.Contains(nb, na) {
+		sim = math.Max(sim, 0.9)
+	}
+	return sim
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}), " ")
+}
+
+func dice(a, b string) float64 {
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	total := 0
+	for _, n := range ba {
+		total += n
+	}
+	for _, n := range bb {
+		total += n
+	}
+
+	shared := 0
+	for g, n := range ba {
+		if m := bb[g]; m < n {
+			shared += m
+		} else {
+			shared += n
+		}
+	}
+	return 2 * float64(shared) / float64(total)
+}
+
+func bigrams(s string) map[string]int {
+	r := []rune(s)
+	out := map[string]int{}
+	for i := 0; i+1 < len(r); i++ {
+		out[string(r[i:i+2])]++
+	}
+	return out
+}
+
+func sameAccount(a, b models.Transaction) bool {
+	if a.AccountID == nil || b.AccountID == nil {
+		return a.AccountID == nil && b.AccountID == nil
+	}
+	return *a.AccountID == *b.AccountID
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,62 @@
+package duplicates
+
+import (
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func TestTitleSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b string
+		min  float64
+		max  float64
+	}{
+		{"STARBUCKS #1234 AMSTERDAM", "Starbucks Amsterdam", 1, 1},
+		{"Albert Heijn 1021", "ALBERT HEIJN", 0.9, 1},
+		{"Spotify", "Spotfy AB", 0.5, 0.9},
+		{"Rent", "Netflix", 0, 0.2},
+		{"1234", "Rent", 0, 0},
+	}
+	for _, c := range cases {
+		if got := TitleSimilarity(c.a, c.b); got < c.min || got > c.max {
+			t.Errorf("TitleSimilarity(%q, %q) = %.2f, want %.2f..%.2f", c.a, c.b, got, c.min, c.max)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	account := uuid.New()
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	manual := models.Transaction{AccountID: &account, Title: "Starbucks", Amount: 4.5, Type: "expense", TransactionDate: day}
+	imported := models.Transaction{AccountID: &account, Title: "STARBUCKS 1234 AMSTERDAM", Amount: 4.5, Type: "expense",
+		TransactionDate: day.AddDate(0, 0, 1), ExternalID: "FIT1"}
+
+	pair, ok := Score(manual, imported, 3)
+	if !ok {
+		t.Fatal("expected a candidate")
+	}
+	if pair.DaysApart != 1 || pair.TitleSim != 0.9 || pair.Score != 0.84 {
+		t.Errorf("got days %d sim %.2f score %.2f, want 1 0.90 0.84", pair.DaysApart, pair.TitleSim, pair.Score)
+	}
+
+	other := account
+	other[0] ^= 0xff
+	rejects := map[string]func(*models.Transaction){
+		"amount":       func(t *models.Transaction) { t.Amount = 4.6 },
+		"type":         func(t *models.Transaction) { t.Type = "income" },
+		"account":      func(t *models.Transaction) { t.AccountID = &other },
+		"window":       func(t *models.Transaction) { t.TransactionDate = day.AddDate(0, 0, 5) },
+		"bank refs":    func(t *models.Transaction) { t.ExternalID = "FIT2" },
+		"account kind": func(t *models.Transaction) { t.AccountID = nil },
+	}
+	for name, change := range rejects {
+		b := manual
+		change(&b)
+		if _, ok := Score(b, imported, 3); ok {
+			t.Errorf("%s: expected no candidate", name)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package forecast projects where a budget's spending will end up by the
+// close of its period: the pace of day-to-day spending so far carried over
+// the days left, plus the recurring expenses still due.
+package forecast
+
+import (
+	"math"
+	"time"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Lookback is how far before the current period history is searched for
+// recurring expenses; three months covers three monthly payments.
+const Lookback = 100 * 24 * time.Hour
+
+// Forecast is the projected end of a budget's current period.
+type Forecast struct {
+	BudgetID    uuid.UUID `json:"budget_id"`
+	PeriodStart string    `json:"period_start"`
+	PeriodEnd   string    `json:"period_end"`
+	Amount      float64   `json:"amount"`
+	Spent       float64   `json:"spent"`
+	// RecurringSpent is the part of Spent that went to recurring expenses;
+	// it is left out of the pace so it is not counted again every day.
+	RecurringSpent float64 `json:"recurring_spent"`
+	// DailyPace is the day-to-day spending per elapsed day.
+	DailyPace     float64      `json:"daily_pace"`
+	DaysRemaining int          `json:"days_remaining"`
+	Upcoming      []Occurrence `json:"upcoming"`
+	// UpcomingTotal sums the recurring expenses still due this period.
+	UpcomingTotal      float64 `json:"upcoming_total"`
+	ProjectedSpend     float64 `json:"projected_spend"`
+	ProjectedPercent   float64 `json:"projected_percent"`
+	ProjectedOverspend float64 `json:"projected_overspend"`
+	OverBudget         bool    `json:"over_budget"`
+}
+
+// Occurrence is one expected payment of a recurring expense.
+type Occurrence struct {
+	Name    string  `json:"name"`
+	Amount  float64 `json:"amount"`
+	Date    string  `json:"date"`
+	Cadence string  `json:"cadence"`
+}
+
+// Service makes forecasts as of the day its clock gives in Location.
+type Service struct {
+	Clock Clock
+	// Location is the timezone days are counted in; nil means UTC.
+	Location *time.Location
+}
+
+// New returns a service running on clock, or on the system clock when
+// clock is nil.
+func New(clock Clock) *Service {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &Service{Clock: clock}
+}
+
+// In returns a service on the same clock counting days in loc.
+func (s *Service) In(loc *time.Location) *Service {
+	return &Service{Clock: s.Clock, Location: loc}
+}
+
+// Today is the current calendar day in the service's location, which
+// budget periods are counted in.
+func (s *Service) Today() time.Time {
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return dateOf(s.Clock.Now().In(loc))
+}
+
+// HistoryStart is the earliest transaction date Budget needs to see for b.
+func (s *Service) HistoryStart(b models.Budget) time.Time {
+	return budgeting.At(b, s.Today()).Start.Add(-Lookback)
+}
+
+// Budget projects b's current period. amount is what the period holds to
+// spend. txs are the expenses counting against the budget from
+// HistoryStart on; those before the period only serve to find recurring
+// expenses.
+func (s *Service) Budget(b models.Budget, amount float64, txs []models.Transaction) Forecast {
+	today := s.Today()
+	w := budgeting.At(b, today)
+	recurring := DetectRecurring(txs, today)
+
+	f := Forecast{
+		BudgetID:    b.ID,
+		PeriodStart: w.Start.Format("2006-01-02"),
+		PeriodEnd:   w.Last().Format("2006-01-02"),
+		Amount:      round(amount),
+		Upcoming:    []Occurrence{},
+	}
+
+	var spent, recurringSpent float64
+	for _, t := range txs {
+		if t.Type != "expense" || t.IsTransfer || !w.Contains(t.TransactionDate) {
+			continue
+		}
+		spent += t.Amount
+		for _, r := range recurring {
+			if r.Matches(t) {
+				recurringSpent += t.Amount
+				break
+			}
+		}
+	}
+
+	elapsed := days(w.Start, today) + 1
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	if elapsed > w.Days() {
+		elapsed = w.Days()
+	}
+	remaining := w.Days() - elapsed
+
+	var pace float64
+	if elapsed > 0 {
+		pace = (spent - recurringSpent) / float64(elapsed)
+	}
+
+	// Payments due after today, and any a few days late, are still to come.
+	// A period that has already ended expects nothing more.
+	var upcoming float64
+	if today.Before(w.End) {
+		for _, r := range recurring {
+			for d := r.Next; d.Before(w.End); d = step(d, r.Cadence) {
+				due := d
+				if due.Before(today) {
+					due = today
+				}
+				f.Upcoming = append(f.Upcoming, Occurrence{
+					Name:    r.Name,
+					Amount:  r.Amount,
+					Date:    due.Format("2006-01-02"),
+					Cadence: r.Cadence,
+				})
+				upcoming += r.Amount
+			}
+		}
+	}
+
+	projected := spent + pace*float64(remaining) + upcoming
+	f.Spent = round(spent)
+	f.RecurringSpent = round(recurringSpent)
+	f.DailyPace = round(pace)
+	f.DaysRemaining = remaining
+	f.UpcomingTotal = round(upcoming)
+	f.ProjectedSpend = round(projected)
+	if amount > 0 {
+		f.ProjectedPercent = round(projected / amount * 100)
+	}
+	if projected > amount {
+		f.ProjectedOverspend = round(projected - amount)
+		f.OverBudget = f.ProjectedOverspend > 0
+	}
+	return f
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func days(a, b time.Time) int {
+	return int(math.Round(dateOf(b).Sub(dateOf(a)).Hours() / 24))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,166 @@
+package forecast
+
+import (
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func expense(date, merchant string, amount float64) models.Transaction {
+	return models.Transaction{Title: merchant, Merchant: merchant, Amount: amount, Type: "expense", TransactionDate: day(date)}
+}
+
+func monthly(amount float64) models.Budget {
+	return models.Budget{Name: "Living", Amount: amount, Period: "monthly", StartDate: day("2026-01-01")}
+}
+
+// history is rent on the 5th and a streaming subscription on the 20th of
+// every month since July, and groceries in October.
+func history() []models.Transaction {
+	return []models.Transaction{
+		expense("2026-07-05", "Landlord", 400),
+		expense("2026-08-05", "Landlord", 400),
+		expense("2026-09-05", "Landlord", 400),
+		expense("2026-10-05", "Landlord", 400),
+		expense("2026-07-20", "Streamly", 15),
+		expense("2026-08-20", "Streamly", 15),
+		expense("2026-09-20", "Streamly", 15),
+		expense("2026-10-02", "Grocer", 50),
+		expense("2026-10-08", "Grocer", 50),
+	}
+}
+
+func TestBudgetPace(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 10, 15, 0, 0, 0, time.UTC))
+	s := New(clock)
+
+	var txs []models.Transaction
+	for d := 1; d <= 10; d++ {
+		txs = append(txs, expense(day("2026-10-01").AddDate(0, 0, d-1).Format("2006-01-02"), "Cafe "+string(rune('A'+d)), 10))
+	}
+	f := s.Budget(monthly(300), 300, txs)
+
+	if f.PeriodStart != "2026-10-01" || f.PeriodEnd != "2026-10-31" {
+		t.Fatalf("period = %s..%s", f.PeriodStart, f.PeriodEnd)
+	}
+	if f.Spent != 100 || f.DailyPace != 10 || f.DaysRemaining != 21 {
+		t.Errorf("spent %v, pace %v, days remaining %d; want 100, 10, 21", f.Spent, f.DailyPace, f.DaysRemaining)
+	}
+	if f.ProjectedSpend != 310 || f.ProjectedOverspend != 10 || !f.OverBudget || f.ProjectedPercent != 103.33 {
+		t.Errorf("projected %v (%v%%), overspend %v, over %v; want 310 (103.33%%), 10, true",
+			f.ProjectedSpend, f.ProjectedPercent, f.ProjectedOverspend, f.OverBudget)
+	}
+}
+
+func TestBudgetRecurring(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 10, 9, 0, 0, 0, time.UTC))
+	f := New(clock).Budget(monthly(800), 800, history())
+
+	// Rent already paid this month stays out of the pace: 100 of groceries
+	// over 10 days, carried over 21 more, plus the subscription on the 20th.
+	if f.Spent != 500 || f.RecurringSpent != 400 || f.DailyPace != 10 {
+		t.Errorf("spent %v, recurring %v, pace %v; want 500, 400, 10", f.Spent, f.RecurringSpent, f.DailyPace)
+	}
+	if len(f.Upcoming) != 1 || f.Upcoming[0].Name != "Streamly" || f.Upcoming[0].Date != "2026-10-20" {
+		t.Fatalf("upcoming = %+v, want Streamly on 2026-10-20", f.Upcoming)
+	}
+	if f.UpcomingTotal != 15 || f.ProjectedSpend != 725 || f.OverBudget {
+		t.Errorf("upcoming %v, projected %v, over %v; want 15, 725, false", f.UpcomingTotal, f.ProjectedSpend, f.OverBudget)
+	}
+}
+
+func TestBudgetLatePaymentStillExpected(t *testing.T) {
+	clock := NewFakeClock(time.Date(2026, 10, 10, 9, 0, 0, 0, time.UTC))
+	s := New(clock)
+
+	clock.Advance(11 * 24 * time.Hour)
+	f := s.Budget(monthly(800), 800, history())
+	if len(f.Upcoming) != 1 || f.Upcoming[0].Date != "2026-10-21" {
+		t.Fatalf("upcoming = %+v, want the late subscription due today", f.Upcoming)
+	}
+
+	// Two months without a payment ends the series.
+	clock.Set(time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC))
+	f = s.Budget(monthly(800), 800, history())
+	for _, o := range f.Upcoming {
+		if o.Name == "Streamly" {
+			t.Errorf("ended subscription still expected: %+v", o)
+		}
+	}
+}
+
+func TestBudgetEndedPeriod(t *testing.T) {
+	b := monthly(800)
+	end := day("2026-10-15")
+	b.EndDate = &end
+
+	f := New(NewFakeClock(day("2026-10-25"))).Budget(b, 800, history())
+	if f.PeriodEnd != "2026-10-15" || f.DaysRemaining != 0 || len(f.Upcoming) != 0 {
+		t.Fatalf("period end %s, days remaining %d, upcoming %+v; want 2026-10-15, 0, none", f.PeriodEnd, f.DaysRemaining, f.Upcoming)
+	}
+	if f.ProjectedSpend != f.Spent {
+		t.Errorf("projected %v, want what was spent, %v", f.ProjectedSpend, f.Spent)
+	}
+}
+
+func TestDetectRecurring(t *testing.T) {
+	now := day("2026-10-10")
+	txs := []models.Transaction{
+		expense("2026-09-19", "Gym", 12),
+		expense("2026-09-26", "Gym", 12),
+		expense("2026-10-03", "Gym", 12.5),
+		// Too few to tell.
+		expense("2026-09-01", "Phone", 20),
+		expense("2026-10-01", "Phone", 20),
+		// Regular, but the amounts are all over the place.
+		expense("2026-08-01", "Market", 20),
+		expense("2026-09-01", "Market", 80),
+		expense("2026-10-01", "Market", 45),
+		// Same amount, irregular days.
+		expense("2026-08-01", "Taxi", 9),
+		expense("2026-08-04", "Taxi", 9),
+		expense("2026-09-28", "Taxi", 9),
+	}
+
+	got := DetectRecurring(txs, now)
+	if len(got) != 1 {
+		t.Fatalf("got %+v, want only the gym", got)
+	}
+	r := got[0]
+	if r.Name != "Gym" || r.Cadence != Weekly || r.Amount != 12 || !r.Next.Equal(day("2026-10-10")) {
+		t.Errorf("got %+v, want Gym weekly at 12, next 2026-10-10", r)
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	c.Advance(2 * time.Hour)
+	if want := start.Add(2 * time.Hour); !c.Now().Equal(want) {
+		t.Errorf("Now = %v, want %v", c.Now(), want)
+	}
+	if s := New(c); !s.Today().Equal(day("2026-02-01")) {
+		t.Errorf("Today = %v, want 2026-02-01", s.Today())
+	}
+}
+
+func TestTodayIn(t *testing.T) {
+	// 01:00 UTC on 1 February is still 31 January in New York.
+	c := NewFakeClock(time.Date(2026, 2, 1, 1, 0, 0, 0, time.UTC))
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := New(c).In(newYork).Today(); !got.Equal(day("2026-01-31")) {
+		t.Errorf("Today = %v, want 2026-01-31", got)
+	}
+}
@@ -0,0 +1,165 @@
+package forecast
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+// Cadences a recurring expense can repeat at.
+const (
+	Weekly   = "weekly"
+	Biweekly = "biweekly"
+	Monthly  = "monthly"
+)
+
+// Recurring is an expense that has come back at a steady cadence, like rent
+// or a subscription.
+type Recurring struct {
+	Name    string    `json:"name"`
+	Amount  float64   `json:"amount"`
+	Cadence string    `json:"cadence"`
+	Last    time.Time `json:"last"`
+	Next    time.Time `json:"next"`
+}
+
+const (
+	// minOccurrences is how often an expense must have been seen before it
+	// counts as recurring.
+	minOccurrences = 3
+	// amountTolerance is how far, as a fraction of the median, an amount
+	// may stray and still belong to the series.
+	amountTolerance = 0.15
+)
+
+// cadence bands, in days between occurrences.
+var cadences = []struct {
+	name     string
+	min, max int
+	days     int
+}{
+	{Weekly, 6, 8, 7},
+	{Biweekly, 13, 15, 14},
+	{Monthly, 27, 33, 30},
+}
+
+// DetectRecurring finds the recurring expenses among txs: at least three
+// payments to the same merchant (or with the same title) of about the same
+// amount at a weekly, fortnightly or monthly rhythm. Series that have
+// missed a payment by more than half a cycle as of now are dropped as
+// ended.
+func DetectRecurring(txs []models.Transaction, now time.Time) []Recurring {
+	groups := map[string][]models.Transaction{}
+	names := map[string]string{}
+	for _, t := range txs {
+		if t.Type != "expense" || t.IsTransfer {
+			continue
+		}
+		key, name := seriesKey(t)
+		if key == "" {
+			continue
+		}
+		groups[key] = append(groups[key], t)
+		names[key] = name
+	}
+
+	var out []Recurring
+	for key, list := range groups {
+		if r, ok := detect(list, now); ok {
+			r.Name = names[key]
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Next.Equal(out[j].Next) {
+			return out[i].Next.Before(out[j].Next)
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func detect(list []models.Transaction, now time.Time) (Recurring, bool) {
+	sort.Slice(list, func(i, j int) bool { return list[i].TransactionDate.Before(list[j].TransactionDate) })
+	if len(list) < minOccurrences {
+		return Recurring{}, false
+	}
+
+	amounts := make([]float64, len(list))
+	for i, t := range list {
+		amounts[i] = t.Amount
+	}
+	amount := median(amounts)
+	for _, a := range amounts {
+		if a < amount*(1-amountTolerance) || a > amount*(1+amountTolerance) {
+			return Recurring{}, false
+		}
+	}
+
+	gaps := make([]float64, 0, len(list)-1)
+	for i := 1; i < len(list); i++ {
+		gaps = append(gaps, float64(days(list[i-1].TransactionDate, list[i].TransactionDate)))
+	}
+	gap := median(gaps)
+	for _, c := range cadences {
+		if gap < float64(c.min) || gap > float64(c.max) {
+			continue
+		}
+		for _, g := range gaps {
+			if g < float64(c.min) || g > float64(c.max) {
+				return Recurring{}, false
+			}
+		}
+
+		last := dateOf(list[len(list)-1].TransactionDate)
+		if days(last, now) > c.days*3/2 {
+			return Recurring{}, false
+		}
+		return Recurring{
+			Amount:  round(amount),
+			Cadence: c.name,
+			Last:    last,
+			Next:    step(last, c.name),
+		}, true
+	}
+	return Recurring{}, false
+}
+
+// Matches reports whether t is a payment of the series.
+func (r Recurring) Matches(t models.Transaction) bool {
+	_, name := seriesKey(t)
+	return strings.EqualFold(name, r.Name) && t.Amount >= r.Amount*(1-amountTolerance) && t.Amount <= r.Amount*(1+amountTolerance)
+}
+
+// seriesKey groups payments by merchant, or by title when the merchant is
+// unknown.
+func seriesKey(t models.Transaction) (key, name string) {
+	name = strings.Join(strings.Fields(t.Merchant), " ")
+	if name == "" {
+		name = strings.Join(strings.Fields(t.Title), " ")
+	}
+	return strings.ToLower(name), name
+}
+
+func step(t time.Time, cadence string) time.Time {
+	switch cadence {
+	case Weekly:
+		return t.AddDate(0, 0, 7)
+	case Biweekly:
+		return t.AddDate(0, 0, 14)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+func median(vs []float64) float64 {
+	s := append([]float64(nil), vs...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
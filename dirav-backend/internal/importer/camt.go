@@ -0,0 +1,252 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// camtDocument covers the parts of an ISO 20022 camt.053 bank-to-customer
+// statement that map onto a Record. Element names are matched without their
+// namespace, so versions 001.02 through 001.08 all decode.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	IBAN    string      `xml:"Acct>Id>IBAN"`
+	Other   string      `xml:"Acct>Id>Othr>Id"`
+	Type    string      `xml:"Acct>Tp>Cd"`
+	Name    string      `xml:"Acct>Nm"`
+	Ccy     string      `xml:"Acct>Ccy"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtEntry struct {
+	Ref          string        `xml:"NtryRef"`
+	Amt          camtAmount    `xml:"Amt"`
+	CdtDbtInd    string        `xml:"CdtDbtInd"`
+	RvslInd      bool          `xml:"RvslInd"`
+	Status       camtStatus    `xml:"Sts"`
+	BookingDate  camtDate      `xml:"BookgDt"`
+	ValueDate    camtDate      `xml:"ValDt"`
+	AcctSvcrRef  string        `xml:"AcctSvcrRef"`
+	Details      []camtDetails `xml:"NtryDtls>TxDtls"`
+	AddtlNtryInf string        `xml:"AddtlNtryInf"`
+}
+
+type camtAmount struct {
+	Value string `xml:",chardata"`
+	Ccy   string `xml:"Ccy,attr"`
+}
+
+// camtStatus is a plain code up to version 001.07 and wrapped in <Cd> from
+// 001.08 on.
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+func (s camtStatus) code() string {
+	if s.Code != "" {
+		return strings.TrimSpace(s.Code)
+	}
+	return strings.TrimSpace(s.Value)
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+func (d camtDate) parse() (time.Time, bool) {
+	raw := strings.TrimSpace(d.Date)
+	if raw == "" && len(strings.TrimSpace(d.DateTime)) >= 10 {
+		raw = strings.TrimSpace(d.DateTime)[:10]
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	return t, err == nil
+}
+
+type camtDetails struct {
+	AcctSvcrRef  string     `xml:"Refs>AcctSvcrRef"`
+	Amt          camtAmount `xml:"Amt"`
+	TxAmt        camtAmount `xml:"AmtDtls>TxAmt>Amt"`
+	CdtDbtInd    string     `xml:"CdtDbtInd"`
+	Creditor     camtParty  `xml:"RltdPties>Cdtr"`
+	Debtor       camtParty  `xml:"RltdPties>Dbtr"`
+	Unstructured []string   `xml:"RmtInf>Ustrd"`
+	Structured   []string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	AddtlTxInf   string     `xml:"AddtlTxInf"`
+}
+
+// camtParty holds the party name directly up to 001.07 and under <Pty> from
+// 001.08 on.
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p camtParty) name() string {
+	if p.PartyName != "" {
+		return strings.TrimSpace(p.PartyName)
+	}
+	return strings.TrimSpace(p.Name)
+}
+
+// ParseCAMT053 reads an ISO 20022 camt.053 statement. Entries that bundle
+// several payments with their own amounts become one Row per payment.
+func ParseCAMT053(data []byte) (*Statement, error) {
+	if !bytes.Contains(data, []byte("BkToCstmrStmt")) {
+		return nil, errors.New("not a camt.053 document")
+	}
+
+	var doc camtDocument
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode camt.053: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, errors.New("camt.053 document has no statements")
+	}
+
+	stmt := &Statement{}
+	first := doc.Statements[0]
+	stmt.Account = StatementAccount{
+		AccountID:   strings.TrimSpace(first.IBAN),
+		AccountType: strings.ToLower(strings.TrimSpace(first.Type)),
+		Name:        strings.TrimSpace(first.Name),
+	}
+	if stmt.Account.AccountID == "" {
+		stmt.Account.AccountID = strings.TrimSpace(first.Other)
+	}
+	stmt.Currency = strings.TrimSpace(first.Ccy)
+
+	line := 0
+	for _, s := range doc.Statements {
+		for _, e := range s.Entries {
+			for _, row := range camtRows(e) {
+				line++
+				row.Line = line
+				stmt.add(row)
+			}
+		}
+	}
+	return stmt, nil
+}
+
+func camtRows(e camtEntry) []Row {
+	split := len(e.Details) > 1
+	for _, d := range e.Details {
+		if d.amount().Value == "" {
+			split = false
+		}
+	}
+
+	if !split {
+		var d camtDetails
+		if len(e.Details) > 0 {
+			d = e.Details[0]
+		}
+		return []Row{camtRow(e, d, e.Amt, e.CdtDbtInd)}
+	}
+
+	rows := make([]Row, 0, len(e.Details))
+	for _, d := range e.Details {
+		ind := d.CdtDbtInd
+		if ind == "" {
+			ind = e.CdtDbtInd
+		}
+		rows = append(rows, camtRow(e, d, d.amount(), ind))
+	}
+	return rows
+}
+
+func (d camtDetails) amount() camtAmount {
+	if strings.TrimSpace(d.Amt.Value) != "" {
+		return d.Amt
+	}
+	return d.TxAmt
+}
+
+func camtRow(e camtEntry, d camtDetails, amt camtAmount, indicator string) Row {
+	var row Row
+
+	if status := e.Status.code(); status != "" && status != "BOOK" {
+		row.Errors = append(row.Errors, fmt.Sprintf("entry not booked (%s)", status))
+	}
+
+	booking, okBooking := e.BookingDate.parse()
+	value, okValue := e.ValueDate.parse()
+	switch {
+	case okBooking:
+		row.Record.Date = booking
+	case okValue:
+		row.Record.Date = value
+	default:
+		row.Errors = append(row.Errors, "missing booking date")
+	}
+	if okValue {
+		row.Record.ValueDate = &value
+	}
+
+	v, err := ParseAmount(amt.Value)
+	switch {
+	case err != nil:
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid amount %q", amt.Value))
+	case v == 0:
+		row.Errors = append(row.Errors, "zero amount")
+	}
+	debit := strings.TrimSpace(indicator) == "DBIT"
+	if e.RvslInd {
+		debit = !debit
+	}
+	if debit {
+		v = -abs(v)
+	} else {
+		v = abs(v)
+	}
+	row.Record.Amount = v
+
+	// The counterparty is whoever is on the other side of the money flow.
+	if strings.TrimSpace(indicator) == "DBIT" {
+		row.Record.Counterparty = d.Creditor.name()
+	} else {
+		row.Record.Counterparty = d.Debtor.name()
+	}
+
+	remittance := append(trimAll(d.Unstructured), trimAll(d.Structured)...)
+	row.Record.Remittance = strings.Join(remittance, " ")
+	if row.Record.Remittance == "" {
+		row.Record.Remittance = firstNonEmpty(d.AddtlTxInf, e.AddtlNtryInf)
+	}
+
+	row.Record.Title = firstNonEmpty(row.Record.Counterparty, row.Record.Remittance, e.AddtlNtryInf)
+	if row.Record.Title == "" {
+		row.Errors = append(row.Errors, "missing title")
+	}
+
+	row.Record.ExternalID = firstNonEmpty(d.AcctSvcrRef, e.AcctSvcrRef, e.Ref)
+	return row
+}
+
+func trimAll(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.Join(strings.Fields(v), " "); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
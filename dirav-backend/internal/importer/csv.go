@@ -0,0 +1,450 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// CSVMapping describes how the columns of a bank's CSV export map onto a
+// Record. Column references are header names when HasHeader is set, or
+// 1-based column numbers otherwise. Empty settings are auto-detected.
+type CSVMapping struct {
+	Delimiter      string `json:"delimiter"`
+	Encoding       string `json:"encoding"`
+	DateFormat     string `json:"date_format"`
+	HasHeader      bool   `json:"has_header"`
+	SkipRows       int    `json:"skip_rows"`
+	DateColumn     string `json:"date_column"`
+	TitleColumn    string `json:"title_column"`
+	AmountColumn   string `json:"amount_column"`
+	DebitColumn    string `json:"debit_column"`
+	CreditColumn   string `json:"credit_column"`
+	CategoryColumn string `json:"category_column"`
+	InvertSign     bool   `json:"invert_sign"`
+}
+
+// CSVPreview is the result of a dry-run parse: the settings that were
+// detected or applied, the header row, and every parsed row.
+type CSVPreview struct {
+	Delimiter           string   `json:"delimiter"`
+	Encoding            string   `json:"encoding"`
+	DateFormat          string   `json:"date_format"`
+	DateFormatAmbiguous bool     `json:"date_format_ambiguous"`
+	Headers             []string `json:"headers"`
+	Rows                []Row    `json:"rows"`
+	InvalidRows         int      `json:"invalid_rows"`
+}
+
+// ErrInvalidSkipRows rejects a mapping that skips a negative number of
+// rows.
+var ErrInvalidSkipRows = errors.New("skip_rows must not be negative")
+
+var delimiterCandidates = []rune{',', ';', '\t', '|'}
+
+// dateLayouts is ordered by preference. When layouts with different day and
+// month order all parse every sample, the earliest wins and the preview is
+// marked ambiguous so the user can confirm it.
+var dateLayouts = []struct {
+	layout   string
+	dayFirst bool
+}{
+	{"2006-01-02", false},
+	{"2006/01/02", false},
+	{"20060102", false},
+	{"2006-01-02T15:04:05", false},
+	{"02/01/2006", true},
+	{"01/02/2006", false},
+	{"02.01.2006", true},
+	{"02-01-2006", true},
+	{"01-02-2006", false},
+	{"2/1/2006", true},
+	{"1/2/2006", false},
+	{"02/01/06", true},
+	{"01/02/06", false},
+	{"2/1/06", true},
+	{"1/2/06", false},
+	{"02.01.06", true},
+	{"02 Jan 2006", true},
+	{"2 Jan 2006", true},
+	{"Jan 2, 2006", false},
+}
+
+// ParseCSV decodes data, detects whatever the mapping leaves unset and parses
+// every row. It only fails when the file as a whole is unreadable; problems
+// with individual rows are reported on the rows themselves.
+func ParseCSV(data []byte, m CSVMapping) (*CSVPreview, error) {
+	if m.SkipRows < 0 {
+		return nil, ErrInvalidSkipRows
+	}
+	text, enc, err := decode(data, m.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	delim, err := resolveDelimiter(m.Delimiter, text)
+	if err != nil {
+		return nil, err
+	}
+
+	r := csv.NewReader(strings.NewReader(text))
+	r.Comma = delim
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	lines, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %w", err)
+	}
+
+	first := 1 + m.SkipRows
+	if m.SkipRows > len(lines) {
+		lines = nil
+	} else {
+		lines = lines[m.SkipRows:]
+	}
+
+	preview := &CSVPreview{
+		Delimiter: string(delim),
+		Encoding:  enc,
+	}
+	if m.HasHeader && len(lines) > 0 {
+		preview.Headers = lines[0]
+		lines = lines[1:]
+		first++
+	}
+
+	cols, err := resolveColumns(m, preview.Headers)
+	if err != nil {
+		return nil, err
+	}
+
+	layout := m.DateFormat
+	if layout == "" {
+		layout, preview.DateFormatAmbiguous = detectDateLayout(column(lines, cols.date))
+	}
+	preview.DateFormat = layout
+
+	for i, fields := range lines {
+		if blank(fields) {
+			continue
+		}
+		row := parseCSVRow(fields, cols, layout, m.InvertSign)
+		row.Line = first + i
+		if !row.Valid() {
+			preview.InvalidRows++
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+
+	return preview, nil
+}
+
+type csvColumns struct {
+	date, title, amount, debit, credit, category int
+}
+
+func resolveColumns(m CSVMapping, headers []string) (csvColumns, error) {
+	var cols csvColumns
+	var err error
+	if cols.date, err = resolveColumn(m.DateColumn, headers); err != nil || cols.date < 0 {
+		return cols, fmt.Errorf("date column: %w", orMissing(err))
+	}
+	if cols.title, err = resolveColumn(m.TitleColumn, headers); err != nil || cols.title < 0 {
+		return cols, fmt.Errorf("title column: %w", orMissing(err))
+	}
+	if cols.amount, err = resolveColumn(m.AmountColumn, headers); err != nil {
+		return cols, fmt.Errorf("amount column: %w", err)
+	}
+	if cols.debit, err = resolveColumn(m.DebitColumn, headers); err != nil {
+		return cols, fmt.Errorf("debit column: %w", err)
+	}
+	if cols.credit, err = resolveColumn(m.CreditColumn, headers); err != nil {
+		return cols, fmt.Errorf("credit column: %w", err)
+	}
+	if cols.category, err = resolveColumn(m.CategoryColumn, headers); err != nil {
+		return cols, fmt.Errorf("category column: %w", err)
+	}
+	if cols.amount < 0 && cols.debit < 0 && cols.credit < 0 {
+		return cols, fmt.Errorf("amount column: %w", errMissingColumn)
+	}
+	return cols, nil
+}
+
+var errMissingColumn = errors.New("not mapped")
+
+func orMissing(err error) error {
+	if err != nil {
+		return err
+	}
+	return errMissingColumn
+}
+
+// resolveColumn returns the zero-based index for ref, or -1 when ref is empty.
+func resolveColumn(ref string, headers []string) (int, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return -1, nil
+	}
+	for i, h := range headers {
+		if strings.EqualFold(strings.TrimSpace(h), ref) {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(ref)
+	if err != nil || n < 1 {
+		return -1, fmt.Errorf("unknown column %q", ref)
+	}
+	return n - 1, nil
+}
+
+func parseCSVRow(fields []string, cols csvColumns, layout string, invert bool) Row {
+	var row Row
+
+	rawDate := field(fields, cols.date)
+	if rawDate == "" {
+		row.Errors = append(row.Errors, "missing date")
+	} else if layout == "" {
+		row.Errors = append(row.Errors, "unrecognised date format")
+	} else if d, err := time.Parse(layout, rawDate); err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid date %q", rawDate))
+	} else {
+		row.Record.Date = d
+	}
+
+	row.Record.Title = field(fields, cols.title)
+	if row.Record.Title == "" {
+		row.Errors = append(row.Errors, "missing title")
+	}
+	row.Record.Category = field(fields, cols.category)
+
+	amount, err := csvAmount(fields, cols)
+	if err != nil {
+		row.Errors = append(row.Errors, err.Error())
+	} else if amount == 0 {
+		row.Errors = append(row.Errors, "zero amount")
+	}
+	if invert {
+		amount = -amount
+	}
+	row.Record.Amount = amount
+
+	return row
+}
+
+// csvAmount reads either a single signed amount column or a pair of
+// debit/credit columns, of which usually only one is filled per row.
+func csvAmount(fields []string, cols csvColumns) (float64, error) {
+	if cols.amount >= 0 {
+		raw := field(fields, cols.amount)
+		v, err := ParseAmount(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount %q", raw)
+		}
+		return v, nil
+	}
+
+	var total float64
+	var found bool
+	if raw := field(fields, cols.debit); raw != "" {
+		v, err := ParseAmount(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid debit %q", raw)
+		}
+		total -= abs(v)
+		found = true
+	}
+	if raw := field(fields, cols.credit); raw != "" {
+		v, err := ParseAmount(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid credit %q", raw)
+		}
+		total += abs(v)
+		found = true
+	}
+	if !found {
+		return 0, fmt.Errorf("missing amount")
+	}
+	return total, nil
+}
+
+func decode(data []byte, name string) (string, string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = detectEncoding(data)
+	}
+
+	var enc encoding.Encoding
+	switch name {
+	case "utf-8", "utf8":
+		return string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))), "utf-8", nil
+	case "utf-16", "utf-16le":
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case "utf-16be":
+		enc = unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	case "windows-1252", "cp1252":
+		enc = charmap.Windows1252
+	case "iso-8859-1", "latin1":
+		enc = charmap.ISO8859_1
+	case "iso-8859-15", "latin9":
+		enc = charmap.ISO8859_15
+	default:
+		return "", "", fmt.Errorf("unsupported encoding %q", name)
+	}
+
+	out, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", "", fmt.Errorf("decode %s: %w", name, err)
+	}
+	return string(out), name, nil
+}
+
+func detectEncoding(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xef\xbb\xbf")):
+		return "utf-8"
+	case bytes.HasPrefix(data, []byte("\xff\xfe")):
+		return "utf-16le"
+	case bytes.HasPrefix(data, []byte("\xfe\xff")):
+		return "utf-16be"
+	case utf8.Valid(data):
+		return "utf-8"
+	default:
+		// Most non-UTF-8 bank exports come from Windows tooling.
+		return "windows-1252"
+	}
+}
+
+func resolveDelimiter(configured, text string) (rune, error) {
+	if configured == "" {
+		return detectDelimiter(text), nil
+	}
+	if configured == `\t` || configured == "tab" {
+		return '\t', nil
+	}
+	r, size := utf8.DecodeRuneInString(configured)
+	if size != len(configured) {
+		return 0, fmt.Errorf("delimiter must be a single character")
+	}
+	return r, nil
+}
+
+// detectDelimiter picks the candidate that splits the first lines of the file
+// into the same, largest number of fields.
+func detectDelimiter(text string) rune {
+	sample := sampleLines(text, 10)
+	best, bestScore := ',', 0
+	for _, d := range delimiterCandidates {
+		r := csv.NewReader(strings.NewReader(sample))
+		r.Comma = d
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+
+		counts := map[int]int{}
+		for {
+			rec, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				break
+			}
+			if len(rec) > 1 {
+				counts[len(rec)]++
+			}
+		}
+
+		for fields, lines := range counts {
+			if score := lines*100 + fields; score > bestScore {
+				best, bestScore = d, score
+			}
+		}
+	}
+	return best
+}
+
+func sampleLines(text string, n int) string {
+	lines := strings.SplitN(text, "\n", n+1)
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// detectDateLayout returns the first layout that parses every sample, and
+// whether a layout with the opposite day/month order would also have.
+func detectDateLayout(samples []string) (string, bool) {
+	if len(samples) == 0 {
+		return "", false
+	}
+
+	found := false
+	var layout string
+	var dayFirst bool
+	for _, candidate := range dateLayouts {
+		if !parsesAll(candidate.layout, samples) {
+			continue
+		}
+		if !found {
+			found = true
+			layout, dayFirst = candidate.layout, candidate.dayFirst
+			continue
+		}
+		if candidate.dayFirst != dayFirst {
+			return layout, true
+		}
+	}
+	return layout, false
+}
+
+func parsesAll(layout string, samples []string) bool {
+	for _, s := range samples {
+		if _, err := time.Parse(layout, s); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func column(lines [][]string, idx int) []string {
+	var out []string
+	for _, fields := range lines {
+		if v := field(fields, idx); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func field(fields []string, idx int) string {
+	if idx < 0 || idx >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[idx])
+}
+
+func blank(fields []string) bool {
+	for _, f := range fields {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAmount(t *testing.T) {
+	cases := map[string]float64{
+		"12.50":      12.5,
+		"-12.50":     -12.5,
+		"1,234.56":   1234.56,
+		"1.234,56":   1234.56,
+		"12,5":       12.5,
+		"1,234":      1234,
+		"(40.00)":    -40,
+		"$ 9.99":     9.99,
+		"15,00 €":    15,
+		"25.00-":     -25,
+		"-1 000,00":  -1000,
+		"+3.20":      3.2,
+		"USD 100.10": 100.1,
+	}
+	for raw, want := range cases {
+		got, err := ParseAmount(raw)
+		if err != nil {
+			t.Errorf("ParseAmount(%q): %v", raw, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseAmount(%q) = %v, want %v", raw, got, want)
+		}
+	}
+
+	if _, err := ParseAmount("n/a"); err == nil {
+		t.Error("expected error for non-numeric amount")
+	}
+}
+
+func TestParseCSVDetectsSettings(t *testing.T) {
+	data := []byte("\xef\xbb\xbfDatum;Omschrijving;Bedrag\n" +
+		"13.01.2025;Albert Heijn;-23,45\n" +
+		"14.01.2025;Salaris;1.500,00\n" +
+		";;\n" +
+		"15.01.2025;;-5,00\n")
+
+	preview, err := ParseCSV(data, CSVMapping{
+		HasHeader:    true,
+		DateColumn:   "datum",
+		TitleColumn:  "Omschrijving",
+		AmountColumn: "3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if preview.Delimiter != ";" || preview.Encoding != "utf-8" || preview.DateFormat != "02.01.2006" {
+		t.Fatalf("unexpected settings: %+v", preview)
+	}
+	if len(preview.Rows) != 3 || preview.InvalidRows != 1 {
+		t.Fatalf("expected 3 rows with 1 invalid, got %d rows, %d invalid", len(preview.Rows), preview.InvalidRows)
+	}
+
+	first := preview.Rows[0]
+	if first.Line != 2 || first.Record.Title != "Albert Heijn" || first.Record.Amount != -23.45 ||
+		!first.Record.Date.Equal(time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected first row: %+v", first)
+	}
+	if preview.Rows[1].Record.Amount != 1500 {
+		t.Errorf("expected 1500, got %v", preview.Rows[1].Record.Amount)
+	}
+	if last := preview.Rows[2]; last.Valid() || last.Line != 5 {
+		t.Errorf("expected invalid row on line 5, got %+v", last)
+	}
+}
+
+func TestParseCSVDebitCreditAndLatin1(t *testing.T) {
+	data := []byte("Date,Description,Debit,Credit\n" +
+		"03/10/2025,Caf\xe9 Central,4.50,\n" +
+		"04/10/2025,Refund,,10.00\n")
+
+	preview, err := ParseCSV(data, CSVMapping{
+		HasHeader:    true,
+		DateColumn:   "Date",
+		TitleColumn:  "Description",
+		DebitColumn:  "Debit",
+		CreditColumn: "Credit",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if preview.Encoding != "windows-1252" {
+		t.Errorf("expected windows-1252, got %s", preview.Encoding)
+	}
+	if !preview.DateFormatAmbiguous {
+		t.Error("expected day/month order to be reported as ambiguous")
+	}
+	if got := preview.Rows[0].Record; got.Title != "Café Central" || got.Amount != -4.5 {
+		t.Errorf("unexpected debit row: %+v", got)
+	}
+	if got := preview.Rows[1].Record.Amount; got != 10 {
+		t.Errorf("expected credit of 10, got %v", got)
+	}
+}
+
+func TestParseCSVRequiresMapping(t *testing.T) {
+	if _, err := ParseCSV([]byte("a,b\n1,2\n"), CSVMapping{DateColumn: "1"}); err == nil {
+		t.Error("expected error when title column is not mapped")
+	}
+}
+
+func TestParseCSVNegativeSkipRows(t *testing.T) {
+	m := CSVMapping{DateColumn: "1", TitleColumn: "2", AmountColumn: "3", SkipRows: -1}
+	if _, err := ParseCSV([]byte("2025-01-02,Coffee,-3\n"), m); err != ErrInvalidSkipRows {
+		t.Errorf("got %v, want ErrInvalidSkipRows", err)
+	}
+}
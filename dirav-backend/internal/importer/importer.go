@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Record is a single statement line, independent of the file format it came
+// from. Amount is signed: negative for money leaving the account. Date is
+// the booking date.
+type Record struct {
+	Date      time.Time  `json:"date"`
+	ValueDate *time.Time `json:"value_date,omitempty"`
+	Title     string     `json:"title"`
+	Amount    float64    `json:"amount"`
+	Category  string     `json:"category,omitempty"`
+	// Counterparty is the other side of the payment and Remittance the
+	// free-text reference the payer attached, where the format has them.
+	Counterparty string `json:"counterparty,omitempty"`
+	Remittance   string `json:"remittance,omitempty"`
+	// ExternalID is the bank's own reference for the line (OFX FITID,
+	// camt AcctSvcrRef, MT940 bank reference), used to recognise lines
+	// that were already imported.
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// Row is a parsed Record together with its position in the source file and
+// any validation problems found while parsing it.
+type Row struct {
+	Line   int      `json:"line"`
+	Record Record   `json:"record"`
+	Errors []string `json:"errors,omitempty"`
+	// Duplicate marks a row whose ExternalID already exists in the target
+	// account; committing skips it.
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+func (r Row) Valid() bool {
+	return len(r.Errors) == 0
+}
+
+// Statement is the parsed contents of a structured statement file such as
+// OFX or QIF.
+type Statement struct {
+	Account     StatementAccount `json:"account"`
+	Currency    string           `json:"currency,omitempty"`
+	Rows        []Row            `json:"rows"`
+	InvalidRows int              `json:"invalid_rows"`
+}
+
+// StatementAccount identifies the bank account a statement belongs to, as far
+// as the file format reveals it.
+type StatementAccount struct {
+	BankID      string `json:"bank_id,omitempty"`
+	AccountID   string `json:"account_id,omitempty"`
+	AccountType string `json:"account_type,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// Key is a stable identifier for matching the statement account against
+// stored accounts. It is empty when the file does not identify the account.
+func (a StatementAccount) Key() string {
+	if a.AccountID == "" {
+		return ""
+	}
+	if a.BankID == "" {
+		return a.AccountID
+	}
+	return a.BankID + ":" + a.AccountID
+}
+
+func (s *Statement) add(row Row) {
+	if !row.Valid() {
+		s.InvalidRows++
+	}
+	s.Rows = append(s.Rows, row)
+}
+
+var errInvalidAmount = errors.New("invalid amount")
+
+// ParseAmount accepts the amount notations banks commonly export: currency
+// symbols, thousands separators, decimal commas, trailing minus signs and
+// accounting-style parentheses.
+func ParseAmount(raw string) (float64, error) {
+	s := strings.TrimSpace(raw)
+	if s == "" {
+		return 0, errInvalidAmount
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',':
+			b.WriteRune(r)
+		case r == '-':
+			negative = !negative
+		}
+	}
+	s = b.String()
+	if s == "" {
+		return 0, errInvalidAmount
+	}
+
+	lastDot := strings.LastIndex(s, ".")
+	lastComma := strings.LastIndex(s, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case lastComma >= 0:
+		// A lone comma followed by one or two digits is a decimal comma;
+		// anything else is a thousands separator.
+		if strings.Count(s, ",") == 1 && len(s)-lastComma-1 <= 2 {
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, errInvalidAmount
+	}
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
@@ -0,0 +1,327 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// mt940Line matches the statement line field :61:. Its parts are the value
+// date, optional booking date (MMDD), debit/credit mark (with R for
+// reversals), optional funds code, amount, transaction type, customer
+// reference and optional bank reference.
+var mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([A-Z][A-Z0-9]{3})([^/]*)(?://(.*))?$`)
+
+var mt940Keys = map[string]bool{
+	"NAME": true, "REMI": true, "CNTP": true, "EREF": true, "MARF": true,
+	"CSID": true, "BENM": true, "ORDP": true, "IREF": true, "TRCD": true,
+	"PURP": true, "ULTC": true, "ULTD": true, "BUSP": true, "ACCW": true,
+}
+
+// ParseMT940 reads a SWIFT MT940 customer statement. Files may contain
+// several statements and may or may not be wrapped in SWIFT {1:}..{4:}
+// blocks. The :86: information field is decoded for the common structured
+// layouts (slash-keyed as used by Dutch and Belgian banks, ?-subfields as
+// used by German banks) and otherwise kept as free text.
+func ParseMT940(data []byte) (*Statement, error) {
+	text, _, err := decode(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := mt940Fields(text)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &Statement{}
+	var cur *Row
+	var curType string
+	line := 0
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		if cur.Record.Title == "" {
+			cur.Record.Title = firstNonEmpty(cur.Record.Remittance, curType)
+		}
+		if cur.Record.Title == "" {
+			cur.Errors = append(cur.Errors, "missing title")
+		}
+		stmt.add(*cur)
+		cur, curType = nil, ""
+	}
+
+	for _, f := range fields {
+		switch f.tag {
+		case "25":
+			if stmt.Account.AccountID == "" {
+				stmt.Account.AccountID = strings.TrimSpace(f.value)
+			}
+		case "60F", "60M":
+			if stmt.Currency == "" && len(f.value) >= 10 {
+				stmt.Currency = f.value[7:10]
+			}
+		case "61":
+			flush()
+			line++
+			row, txType := mt940Row(f.value)
+			row.Line = line
+			cur, curType = &row, txType
+		case "86":
+			if cur != nil {
+				counterparty, remittance := mt940Info(f.value)
+				cur.Record.Counterparty = counterparty
+				cur.Record.Remittance = remittance
+				cur.Record.Title = counterparty
+			}
+		case "62F", "62M", "64", "65":
+			flush()
+		}
+	}
+	flush()
+
+	// Some banks append the currency to the IBAN in :25:; drop it so the
+	// account matches the same IBAN from camt.053 statements.
+	if c := stmt.Currency; c != "" && len(stmt.Account.AccountID) > len(c)+15 {
+		stmt.Account.AccountID = strings.TrimSuffix(stmt.Account.AccountID, c)
+	}
+	return stmt, nil
+}
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// mt940Fields splits the message text into :tag: fields, joining continuation
+// lines with newlines.
+func mt940Fields(text string) ([]mt940Field, error) {
+	var fields []mt940Field
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r ")
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			line = line[i+3:]
+		}
+		if line == "" || line == "-" || line == "-}" || strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		if strings.HasPrefix(line, ":") {
+			if end := strings.Index(line[1:], ":"); end > 0 {
+				fields = append(fields, mt940Field{tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read mt940: %w", err)
+	}
+
+	for _, f := range fields {
+		if f.tag == "25" {
+			return fields, nil
+		}
+	}
+	return nil, errors.New("not an MT940 statement")
+}
+
+// mt940Row parses a :61: statement line and also returns its SWIFT
+// transaction type code, the title of last resort.
+func mt940Row(value string) (Row, string) {
+	var row Row
+
+	first, _, _ := strings.Cut(value, "\n")
+	m := mt940Line.FindStringSubmatch(strings.TrimSpace(first))
+	if m == nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("unrecognised statement line %q", first))
+		return row, ""
+	}
+
+	valueDate, err := time.Parse("060102", m[1])
+	if err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid value date %q", m[1]))
+	} else {
+		row.Record.ValueDate = &valueDate
+		row.Record.Date = valueDate
+		if m[2] != "" {
+			if booking, ok := mt940BookingDate(valueDate, m[2]); ok {
+				row.Record.Date = booking
+			} else {
+				row.Errors = append(row.Errors, fmt.Sprintf("invalid booking date %q", m[2]))
+			}
+		}
+	}
+
+	amount, err := ParseAmount(m[5])
+	if err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid amount %q", m[5]))
+	} else if amount == 0 {
+		row.Errors = append(row.Errors, "zero amount")
+	}
+	// A reversal of a credit takes money out, a reversal of a debit puts it
+	// back.
+	if m[3] == "D" || m[3] == "RC" {
+		amount = -amount
+	}
+	row.Record.Amount = amount
+
+	if ref := strings.TrimSpace(m[8]); ref != "" && ref != "NONREF" {
+		row.Record.ExternalID = ref
+	}
+	return row, m[6]
+}
+
+// mt940BookingDate places the MMDD booking date in the year closest to the
+// value date, since bookings may cross the new year either way.
+func mt940BookingDate(valueDate time.Time, mmdd string) (time.Time, bool) {
+	var best time.Time
+	found := false
+	for _, year := range []int{valueDate.Year() - 1, valueDate.Year(), valueDate.Year() + 1} {
+		t, err := time.Parse("20060102", fmt.Sprintf("%04d%s", year, mmdd))
+		if err != nil {
+			continue
+		}
+		if !found || absDuration(t.Sub(valueDate)) < absDuration(best.Sub(valueDate)) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// mt940Info extracts the counterparty name and remittance text from :86:.
+func mt940Info(value string) (string, string) {
+	flat := strings.ReplaceAll(value, "\n", "")
+
+	if len(flat) > 4 && flat[3] == '?' && isDigits(flat[:3]) {
+		return mt940Subfields(flat[3:])
+	}
+	if strings.HasPrefix(flat, "/") {
+		if counterparty, remittance, ok := mt940SlashKeys(flat); ok {
+			return counterparty, remittance
+		}
+	}
+	return "", strings.Join(strings.Fields(strings.ReplaceAll(value, "\n", " ")), " ")
+}
+
+// mt940Subfields decodes the German ?NN layout: ?20-?29 and ?60-?63 carry the
+// remittance text, ?32-?33 the counterparty name.
+func mt940Subfields(value string) (string, string) {
+	var name, remittance strings.Builder
+	for _, part := range strings.Split(value, "?") {
+		if len(part) < 2 || !isDigits(part[:2]) {
+			continue
+		}
+		code, text := part[:2], part[2:]
+		switch {
+		case code == "32" || code == "33":
+			name.WriteString(text)
+		case (code >= "20" && code <= "29") || (code >= "60" && code <= "63"):
+			remittance.WriteString(text)
+		}
+	}
+
+	r := remittance.String()
+	// SEPA remittance is prefixed with a key such as SVWZ+; keep only the
+	// text after the SVWZ key when present.
+	if i := strings.Index(r, "SVWZ+"); i >= 0 {
+		r = r[i+5:]
+		if j := strings.Index(r, "+"); j > 4 && isUpper(r[j-4:j]) {
+			r = r[:j-4]
+		}
+	}
+	return strings.TrimSpace(name.String()), strings.TrimSpace(r)
+}
+
+// mt940SlashKeys decodes the /KEY/value layout. CNTP holds
+// account/BIC/name/city, the other keys a single value.
+func mt940SlashKeys(value string) (string, string, bool) {
+	parts := strings.Split(value, "/")
+	values := map[string][]string{}
+	key := ""
+	for _, p := range parts {
+		if mt940Keys[p] {
+			key = p
+			continue
+		}
+		if key != "" {
+			values[key] = append(values[key], p)
+		}
+	}
+	if len(values) == 0 {
+		return "", "", false
+	}
+
+	name := strings.Join(nonEmpty(values["NAME"]), " ")
+	if name == "" {
+		if cntp := values["CNTP"]; len(cntp) >= 3 {
+			name = cntp[2]
+		}
+	}
+	if name == "" {
+		for _, k := range []string{"BENM", "ORDP"} {
+			if v := nonEmpty(values[k]); len(v) > 0 {
+				// BENM and ORDP start with a NAME subkey in some layouts;
+				// that subkey is consumed above, so take the first value.
+				name = v[0]
+				break
+			}
+		}
+	}
+
+	// REMI is usually USTD//free text or STRD/CUR/reference; the text
+	// itself may contain slashes, so it is re-joined rather than filtered.
+	remi := values["REMI"]
+	if len(remi) > 0 && (remi[0] == "USTD" || remi[0] == "STRD") {
+		remi = remi[1:]
+		if len(remi) > 0 && (remi[0] == "" || remi[0] == "CUR") {
+			remi = remi[1:]
+		}
+	}
+	remittance := strings.Trim(strings.Join(remi, "/"), "/ ")
+	return strings.TrimSpace(name), remittance, true
+}
+
+func nonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isUpper(s string) bool {
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return s != ""
+}
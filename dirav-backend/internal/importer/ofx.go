@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// ParseOFX reads an OFX or QFX statement. Both the SGML flavour of OFX 1.x,
+// where leaf elements are never closed, and the XML flavour of OFX 2.x are
+// accepted, since the parser only looks at element names and their text.
+func ParseOFX(data []byte) (*Statement, error) {
+	start := bytes.IndexByte(data, '<')
+	if start < 0 || !bytes.Contains(bytes.ToUpper(data), []byte("<OFX")) {
+		return nil, errors.New("not an OFX document")
+	}
+
+	stmt := &Statement{}
+	var txn map[string]string
+	var inLedger bool
+	line := 0
+
+	for _, tok := range tokenizeOFX(data[start:]) {
+		switch tok.name {
+		case "STMTTRN":
+			txn = map[string]string{}
+			line++
+			continue
+		case "/STMTTRN":
+			if txn != nil {
+				stmt.add(ofxRow(txn, line))
+			}
+			txn = nil
+			continue
+		case "LEDGERBAL":
+			inLedger = true
+			continue
+		case "/LEDGERBAL":
+			inLedger = false
+			continue
+		}
+
+		if tok.value == "" || strings.HasPrefix(tok.name, "/") {
+			continue
+		}
+		if txn != nil {
+			// PAYEE aggregates carry their own NAME; keep the first one seen.
+			if _, seen := txn[tok.name]; !seen {
+				txn[tok.name] = tok.value
+			}
+			continue
+		}
+		if inLedger {
+			continue
+		}
+
+		switch tok.name {
+		case "BANKID":
+			stmt.Account.BankID = tok.value
+		case "ACCTID":
+			stmt.Account.AccountID = tok.value
+		case "ACCTTYPE":
+			stmt.Account.AccountType = strings.ToLower(tok.value)
+		case "CURDEF":
+			stmt.Currency = tok.value
+		}
+	}
+
+	if stmt.Account.AccountType == "" && bytes.Contains(bytes.ToUpper(data), []byte("<CCACCTFROM>")) {
+		stmt.Account.AccountType = "credit"
+	}
+	return stmt, nil
+}
+
+func ofxRow(f map[string]string, line int) Row {
+	row := Row{Line: line}
+
+	raw := f["DTPOSTED"]
+	if raw == "" {
+		raw = f["DTUSER"]
+	}
+	if d, err := parseOFXDate(raw); err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid date %q", raw))
+	} else {
+		row.Record.Date = d
+	}
+
+	if v, err := ParseAmount(f["TRNAMT"]); err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid amount %q", f["TRNAMT"]))
+	} else if v == 0 {
+		row.Errors = append(row.Errors, "zero amount")
+	} else {
+		row.Record.Amount = v
+	}
+
+	row.Record.Title = f["NAME"]
+	if row.Record.Title == "" {
+		row.Record.Title = f["MEMO"]
+	}
+	if row.Record.Title == "" {
+		row.Errors = append(row.Errors, "missing title")
+	}
+
+	row.Record.ExternalID = f["FITID"]
+	return row
+}
+
+// parseOFXDate reads the date part of an OFX datetime, which looks like
+// YYYYMMDD[HHMMSS[.XXX]][[-5:EST]]. Statement lines are stored as dates, so
+// the time and zone are ignored.
+func parseOFXDate(raw string) (time.Time, error) {
+	if len(raw) < 8 {
+		return time.Time{}, errors.New("short date")
+	}
+	return time.Parse("20060102", raw[:8])
+}
+
+type ofxToken struct {
+	name  string
+	value string
+}
+
+// tokenizeOFX splits a document into tags and the text following each of
+// them. Closing tags are returned with a leading slash.
+func tokenizeOFX(data []byte) []ofxToken {
+	var tokens []ofxToken
+	for len(data) > 0 {
+		open := bytes.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		data = data[open+1:]
+
+		end := bytes.IndexByte(data, '>')
+		if end < 0 {
+			break
+		}
+		tag := string(data[:end])
+		data = data[end+1:]
+
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+		if i := strings.IndexAny(tag, " \t\r\n"); i >= 0 {
+			tag = tag[:i]
+		}
+		tag = strings.TrimSuffix(tag, "/")
+
+		next := bytes.IndexByte(data, '<')
+		if next < 0 {
+			next = len(data)
+		}
+		value := strings.TrimSpace(html.UnescapeString(string(data[:next])))
+
+		tokens = append(tokens, ofxToken{name: strings.ToUpper(tag), value: value})
+	}
+	return tokens
+}
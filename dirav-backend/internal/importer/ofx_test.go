@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+const sgmlOFX = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM>
+<BANKID>121000248
+<ACCTID>000123456789
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20251001
+<DTEND>20251031
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20251003120000.000[-5:EST]
+<TRNAMT>-150.00
+<FITID>2025100301
+<NAME>CAMPUS BOOKSTORE
+<MEMO>Textbooks
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20251005
+<TRNAMT>1200.00
+<FITID>2025100502
+<MEMO>Payroll &amp; stipend
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>2450.00<DTASOF>20251031</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlOFX = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CURDEF>EUR</CURDEF>
+<CCACCTFROM><ACCTID>4111XXXX1111</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20250214</DTPOSTED><TRNAMT>-4.20</TRNAMT><FITID>A1</FITID><PAYEE><NAME>Coffee Lab</NAME></PAYEE></STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>bad</DTPOSTED><TRNAMT>-1.00</TRNAMT><FITID>A2</FITID><NAME>Broken</NAME></STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>
+`
+
+func TestParseOFXSGML(t *testing.T) {
+	stmt, err := ParseOFX([]byte(sgmlOFX))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key := stmt.Account.Key(); key != "121000248:000123456789" {
+		t.Errorf("unexpected account key %q", key)
+	}
+	if stmt.Account.AccountType != "checking" || stmt.Currency != "USD" {
+		t.Errorf("unexpected account info: %+v %s", stmt.Account, stmt.Currency)
+	}
+	if len(stmt.Rows) != 2 || stmt.InvalidRows != 0 {
+		t.Fatalf("expected 2 valid rows, got %+v", stmt.Rows)
+	}
+
+	first := stmt.Rows[0].Record
+	if first.Title != "CAMPUS BOOKSTORE" || first.Amount != -150 || first.ExternalID != "2025100301" ||
+		!first.Date.Equal(time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected first record: %+v", first)
+	}
+	if second := stmt.Rows[1].Record; second.Title != "Payroll & stipend" || second.Amount != 1200 {
+		t.Errorf("expected memo fallback title, got %+v", second)
+	}
+}
+
+func TestParseOFXXML(t *testing.T) {
+	stmt, err := ParseOFX([]byte(xmlOFX))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stmt.Account.Key() != "4111XXXX1111" || stmt.Account.AccountType != "credit" || stmt.Currency != "EUR" {
+		t.Errorf("unexpected account info: %+v %s", stmt.Account, stmt.Currency)
+	}
+	if len(stmt.Rows) != 2 || stmt.InvalidRows != 1 {
+		t.Fatalf("expected 2 rows with 1 invalid, got %+v", stmt.Rows)
+	}
+	if got := stmt.Rows[0].Record; got.Title != "Coffee Lab" || got.ExternalID != "A1" {
+		t.Errorf("unexpected payee record: %+v", got)
+	}
+}
+
+func TestParseOFXRejectsOtherFiles(t *testing.T) {
+	if _, err := ParseOFX([]byte("date,amount\n")); err == nil {
+		t.Error("expected error for non-OFX input")
+	}
+}
@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ParseQIF reads a Quicken Interchange Format file. Only cash, bank and
+// credit card sections are imported; investment and list sections are
+// skipped. QIF dates carry no locale marker, so the date layout is detected
+// across all entries the same way as for CSV, unless dateFormat is given.
+func ParseQIF(data []byte, dateFormat string) (*Statement, error) {
+	text, _, err := decode(data, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &Statement{}
+	var entries []qifEntry
+	var cur qifEntry
+	var inAccount, skip, sawHeader bool
+
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			sawHeader = true
+			header := strings.ToLower(strings.TrimSpace(line))
+			inAccount = header == "!account"
+			skip = !inAccount && !isQIFCashSection(header)
+			cur = qifEntry{}
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		if code == '^' {
+			if !inAccount && !skip && cur.started {
+				entries = append(entries, cur)
+			}
+			cur = qifEntry{}
+			continue
+		}
+
+		if inAccount {
+			switch code {
+			case 'N':
+				stmt.Account.Name = value
+			case 'T':
+				stmt.Account.AccountType = strings.ToLower(value)
+			}
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		if !cur.started {
+			cur = qifEntry{started: true, line: lineNo}
+		}
+		switch code {
+		case 'D':
+			cur.date = normalizeQIFDate(value)
+		case 'T', 'U':
+			if cur.amount == "" {
+				cur.amount = value
+			}
+		case 'P':
+			cur.payee = value
+		case 'M':
+			cur.memo = value
+		case 'L':
+			cur.category = value
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read qif: %w", err)
+	}
+	if !sawHeader {
+		return nil, errors.New("not a QIF file")
+	}
+	if cur.started && !skip && !inAccount {
+		entries = append(entries, cur)
+	}
+
+	layout := dateFormat
+	if layout == "" {
+		var dates []string
+		for _, e := range entries {
+			if e.date != "" {
+				dates = append(dates, e.date)
+			}
+		}
+		layout, _ = detectDateLayout(dates)
+	}
+
+	for _, e := range entries {
+		stmt.add(e.row(layout))
+	}
+	return stmt, nil
+}
+
+type qifEntry struct {
+	started  bool
+	line     int
+	date     string
+	amount   string
+	payee    string
+	memo     string
+	category string
+}
+
+func (e qifEntry) row(layout string) Row {
+	row := Row{Line: e.line}
+
+	if e.date == "" {
+		row.Errors = append(row.Errors, "missing date")
+	} else if layout == "" {
+		row.Errors = append(row.Errors, "unrecognised date format")
+	} else if d, err := time.Parse(layout, e.date); err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid date %q", e.date))
+	} else {
+		row.Record.Date = d
+	}
+
+	if v, err := ParseAmount(e.amount); err != nil {
+		row.Errors = append(row.Errors, fmt.Sprintf("invalid amount %q", e.amount))
+	} else if v == 0 {
+		row.Errors = append(row.Errors, "zero amount")
+	} else {
+		row.Record.Amount = v
+	}
+
+	row.Record.Title = e.payee
+	if row.Record.Title == "" {
+		row.Record.Title = e.memo
+	}
+	if row.Record.Title == "" {
+		row.Errors = append(row.Errors, "missing title")
+	}
+
+	// Transfers are written as [Account Name]; they carry no category.
+	if !strings.HasPrefix(e.category, "[") {
+		row.Record.Category = e.category
+	}
+	return row
+}
+
+func isQIFCashSection(header string) bool {
+	switch header {
+	case "!type:bank", "!type:cash", "!type:ccard", "!type:oth a", "!type:oth l":
+		return true
+	}
+	return false
+}
+
+// normalizeQIFDate rewrites Quicken's 1/2'25 shorthand for 21st-century years
+// and space padding into a form the common date layouts accept.
+func normalizeQIFDate(raw string) string {
+	raw = strings.ReplaceAll(raw, " ", "")
+	if i := strings.IndexByte(raw, '\''); i >= 0 {
+		year := raw[i+1:]
+		if len(year) == 1 {
+			year = "0" + year
+		}
+		if len(year) == 2 {
+			year = "20" + year
+		}
+		raw = raw[:i] + "/" + year
+	}
+	return raw
+}
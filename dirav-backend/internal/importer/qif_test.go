@@ -0,0 +1,70 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+const sampleQIF = `!Account
+NStudent Checking
+TBank
+^
+!Type:Bank
+D10/ 3'25
+T-150.00
+PCampus Bookstore
+LEducation
+^
+D10/15'25
+T1,200.00
+MPart-time job
+^
+D10/20'25
+T-50.00
+PTransfer to savings
+L[Savings]
+^
+!Type:Invst
+D10/21'25
+NBuy
+YACME
+^
+`
+
+func TestParseQIF(t *testing.T) {
+	stmt, err := ParseQIF([]byte(sampleQIF), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stmt.Account.Name != "Student Checking" || stmt.Account.AccountType != "bank" {
+		t.Errorf("unexpected account: %+v", stmt.Account)
+	}
+	if len(stmt.Rows) != 3 || stmt.InvalidRows != 0 {
+		t.Fatalf("expected 3 valid rows, got %+v", stmt.Rows)
+	}
+
+	first := stmt.Rows[0]
+	if first.Line != 6 || first.Record.Title != "Campus Bookstore" || first.Record.Category != "Education" ||
+		first.Record.Amount != -150 || !first.Record.Date.Equal(time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected first row: %+v", first)
+	}
+	if got := stmt.Rows[1].Record; got.Title != "Part-time job" || got.Amount != 1200 {
+		t.Errorf("unexpected income row: %+v", got)
+	}
+	if got := stmt.Rows[2].Record; got.Category != "" {
+		t.Errorf("expected transfer category to be dropped, got %q", got.Category)
+	}
+}
+
+func TestParseQIFExplicitDateFormat(t *testing.T) {
+	data := "!Type:Cash\nD03/10/2025\nT-2.50\nPBus\n^\n"
+
+	stmt, err := ParseQIF([]byte(data), "02/01/2006")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stmt.Rows[0].Record.Date; !got.Equal(time.Date(2025, 10, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected 3 Oct 2025, got %s", got)
+	}
+}
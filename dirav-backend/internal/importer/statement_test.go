@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// TestStatementGolden parses every sample statement in testdata and compares
+// the result with its .golden.json file. Run with -update after an intended
+// change to the output.
+func TestStatementGolden(t *testing.T) {
+	parsers := map[string]func([]byte) (*Statement, error){
+		".xml": ParseCAMT053,
+		".sta": ParseMT940,
+	}
+
+	files, err := os.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range files {
+		parse, ok := parsers[filepath.Ext(f.Name())]
+		if !ok {
+			continue
+		}
+		name := f.Name()
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmt, err := parse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.MarshalIndent(stmt, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", strings.TrimSuffix(name, filepath.Ext(name))+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestStatementParsersRejectOtherFormats(t *testing.T) {
+	if _, err := ParseCAMT053([]byte(sgmlOFX)); err == nil {
+		t.Error("expected camt.053 parser to reject OFX")
+	}
+	if _, err := ParseMT940([]byte(sampleQIF)); err == nil {
+		t.Error("expected MT940 parser to reject QIF")
+	}
+}
@@ -8,15 +8,16 @@ import (
 )
 
 type Account struct {
-	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
-	UserID      uuid.UUID `gorm:"type:uuid;index;not null"`
-	AccountName string    `gorm:"not null"`
-	AccountType string    `gorm:"not null"`
-	Balance     float64   `gorm:"not null"`
-	Currency    string    `gorm:"default:USD"`
-	IsPrimary   bool      `gorm:"default:false"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID                uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID            uuid.UUID `gorm:"type:uuid;index;not null"`
+	AccountName       string    `gorm:"not null"`
+	AccountType       string    `gorm:"not null"`
+	Balance           float64   `gorm:"not null"`
+	Currency          string    `gorm:"default:USD"`
+	IsPrimary         bool      `gorm:"default:false"`
+	ExternalAccountID string    `gorm:"index"`
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
 }
 
 func (a *Account) BeforeCreate(tx *gorm.DB) (err error) {
@@ -14,11 +14,16 @@ type Budget struct {
 	Amount    float64   `gorm:"not null"`
 	Period    string    `gorm:"not null"`
 	Category  string
-	StartDate time.Time `gorm:"not null"`
+	AccountID *uuid.UUID `gorm:"type:uuid;index"`
+	StartDate time.Time  `gorm:"not null"`
 	EndDate   *time.Time
-	IsActive  bool `gorm:"default:true"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	IsActive  bool   `gorm:"default:true"`
+	Rollover  string `gorm:"not null;default:none"`
+	// AlertThresholds are the percentages of Amount that raise an alert.
+	// Nil means the defaults; an empty list turns alerts off.
+	AlertThresholds []int `gorm:"serializer:json"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 func (b *Budget) BeforeCreate(tx *gorm.DB) (err error) {
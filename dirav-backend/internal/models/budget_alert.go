@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BudgetAlert records that a budget reached one of its thresholds in a
+// period. The unique index makes each threshold fire once per period.
+type BudgetAlert struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	BudgetID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_budget_alert"`
+	PeriodStart time.Time `gorm:"not null;uniqueIndex:idx_budget_alert"`
+	Threshold   int       `gorm:"not null;uniqueIndex:idx_budget_alert"`
+	Kind        string    `gorm:"not null"`
+	PercentUsed float64   `gorm:"not null"`
+	Spent       float64   `gorm:"not null"`
+	Amount      float64   `gorm:"not null"`
+	DeliveredAt *time.Time
+	ReadAt      *time.Time
+	CreatedAt   time.Time
+}
+
+func (a *BudgetAlert) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
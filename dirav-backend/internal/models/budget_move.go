@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BudgetMove records money moved from one budget's current period to
+// another's.
+type BudgetMove struct {
+	ID           uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	FromBudgetID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ToBudgetID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	Amount       float64   `gorm:"not null"`
+	Note         string
+	CreatedAt    time.Time
+}
+
+func (m *BudgetMove) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BudgetPeriod is the snapshot of one budget period: what was budgeted,
+// carried over from the period before under Rollover, moved in or out from
+// other budgets and spent. A closed period keeps its Budgeted and Rollover;
+// spending and carry-over are refreshed as transactions change. Stale marks
+// a closed period whose transactions changed since it was worked out.
+type BudgetPeriod struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	BudgetID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_budget_period"`
+	PeriodStart time.Time `gorm:"not null;uniqueIndex:idx_budget_period"`
+	PeriodEnd   time.Time `gorm:"not null"`
+	Budgeted    float64   `gorm:"not null;default:0"`
+	Rollover    string    `gorm:"not null;default:''"`
+	CarriedOver float64   `gorm:"not null;default:0"`
+	Moved       float64   `gorm:"not null;default:0"`
+	Spent       float64   `gorm:"not null;default:0"`
+	Available   float64   `gorm:"not null;default:0"`
+	Closed      bool      `gorm:"not null;default:false"`
+	Stale       bool      `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (p *BudgetPeriod) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BudgetTemplate is a starter set of budgets written for a monthly
+// allowance. Built-in templates are seeded at startup; admins can edit them
+// and add their own.
+type BudgetTemplate struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Slug        string    `gorm:"uniqueIndex;not null"`
+	Name        string    `gorm:"not null"`
+	Description string
+	Allowance   float64              `gorm:"not null"`
+	Budgets     []BudgetTemplateItem `gorm:"serializer:json"`
+	IsActive    bool                 `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// BudgetTemplateItem is one budget of a template. Its amount is per
+// period, for the template's allowance.
+type BudgetTemplateItem struct {
+	Name     string
+	Category string
+	Amount   float64
+	Period   string
+	Rollover string
+}
+
+func (t *BudgetTemplate) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return
+}
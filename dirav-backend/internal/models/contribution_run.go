@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContributionRun records one scheduled run. The unique schedule and date
+// keep a run from happening twice, however often the worker looks.
+type ContributionRun struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ScheduleID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_contribution_run"`
+	RunDate        time.Time  `gorm:"not null;uniqueIndex:idx_contribution_run"`
+	Status         string     `gorm:"not null"`
+	ContributionID *uuid.UUID `gorm:"type:uuid"`
+	Amount         float64    `gorm:"not null;default:0"`
+	Error          string
+	CreatedAt      time.Time
+}
+
+func (r *ContributionRun) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContributionSchedule puts a fixed amount into a savings goal at a
+// regular cadence, from AccountID and into SavingsAccountID when set.
+// Status is active, paused, or completed once the goal is reached and
+// StopWhenCompleted is set.
+type ContributionSchedule struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID            uuid.UUID  `gorm:"type:uuid;index;not null"`
+	SavingsGoalID     uuid.UUID  `gorm:"type:uuid;index;not null"`
+	AccountID         uuid.UUID  `gorm:"type:uuid;not null"`
+	SavingsAccountID  *uuid.UUID `gorm:"type:uuid"`
+	Amount            float64    `gorm:"not null"`
+	Cadence           string     `gorm:"not null"`
+	StartDate         time.Time  `gorm:"not null"`
+	NextRunDate       time.Time  `gorm:"index;not null"`
+	StopWhenCompleted bool       `gorm:"not null"`
+	Status            string     `gorm:"index;not null"`
+	Note              string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (s *ContributionSchedule) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DuplicateDismissal records a pair of transactions the user reviewed and
+// marked as not being duplicates, so the pair leaves the review queue.
+type DuplicateDismissal struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	FirstID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_duplicate_dismissal_pair"`
+	SecondID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_duplicate_dismissal_pair"`
+	CreatedAt time.Time
+}
+
+func (d *DuplicateDismissal) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
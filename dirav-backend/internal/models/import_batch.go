@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImportBatch groups the transactions created by one statement import so the
+// import can be undone as a unit.
+type ImportBatch struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	AccountID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ProfileID      *uuid.UUID `gorm:"type:uuid"`
+	Source         string     `gorm:"not null"`
+	FileName       string
+	RowCount       int     `gorm:"not null"`
+	DuplicateCount int     `gorm:"not null;default:0"`
+	NetAmount      float64 `gorm:"not null"`
+	Status         string  `gorm:"not null;default:committed"`
+	UndoneAt       *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (b *ImportBatch) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImportProfile is a saved column mapping for one bank's CSV export.
+type ImportProfile struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name           string    `gorm:"not null"`
+	Delimiter      string
+	Encoding       string
+	DateFormat     string
+	HasHeader      bool
+	SkipRows       int    `gorm:"default:0"`
+	DateColumn     string `gorm:"not null"`
+	TitleColumn    string `gorm:"not null"`
+	AmountColumn   string
+	DebitColumn    string
+	CreditColumn   string
+	CategoryColumn string
+	InvertSign     bool `gorm:"default:false"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (p *ImportProfile) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MonthlyPlan splits one month's income across budgets and savings goals.
+// Without ExpectedIncome the plan works from the income transactions
+// recorded in the month.
+type MonthlyPlan struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_plan_month"`
+	Month          time.Time `gorm:"not null;uniqueIndex:idx_plan_month"`
+	ExpectedIncome *float64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (p *MonthlyPlan) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
+}
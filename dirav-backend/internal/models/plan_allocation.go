@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PlanAllocation assigns part of a monthly plan's income to either a budget
+// or a savings goal.
+type PlanAllocation struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	PlanID        uuid.UUID  `gorm:"type:uuid;not null;index;uniqueIndex:idx_plan_budget;uniqueIndex:idx_plan_savings"`
+	UserID        uuid.UUID  `gorm:"type:uuid;index;not null"`
+	BudgetID      *uuid.UUID `gorm:"type:uuid;index;uniqueIndex:idx_plan_budget"`
+	SavingsGoalID *uuid.UUID `gorm:"type:uuid;index;uniqueIndex:idx_plan_savings"`
+	Amount        float64    `gorm:"not null"`
+	CreatedAt     time.Time
+}
+
+func (a *PlanAllocation) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
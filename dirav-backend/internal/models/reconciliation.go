@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reconciliation is one pass of matching an account against a bank
+// statement. Completing it locks the cleared transactions it covered.
+type Reconciliation struct {
+	ID               uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID           uuid.UUID `gorm:"type:uuid;index;not null"`
+	AccountID        uuid.UUID `gorm:"type:uuid;index;not null"`
+	StatementDate    time.Time `gorm:"not null"`
+	StatementBalance float64   `gorm:"not null"`
+	OpeningBalance   float64   `gorm:"not null"`
+	Status           string    `gorm:"not null;default:open"`
+	TransactionCount int       `gorm:"not null;default:0"`
+	CompletedAt      *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (r *Reconciliation) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
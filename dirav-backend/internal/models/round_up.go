@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RoundUp is the spare change of one expense, set aside for a savings goal.
+// It is pending until a daily sweep puts it into the goal as part of a
+// contribution.
+type RoundUp struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	TransactionID  uuid.UUID  `gorm:"type:uuid;uniqueIndex;not null"`
+	SavingsGoalID  uuid.UUID  `gorm:"type:uuid;index;not null"`
+	Amount         float64    `gorm:"not null"`
+	Date           time.Time  `gorm:"not null"`
+	ContributionID *uuid.UUID `gorm:"type:uuid;index"`
+	CreatedAt      time.Time
+}
+
+func (r *RoundUp) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type Rule struct {
+	ID              uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID          uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name            string    `gorm:"not null"`
+	Priority        int       `gorm:"not null;default:0"`
+	IsActive        bool      `gorm:"not null"`
+	TitleContains   string
+	TitleRegex      string
+	MinAmount       *float64
+	MaxAmount       *float64
+	AccountID       *uuid.UUID `gorm:"type:uuid"`
+	TransactionType string
+	SetCategory     string
+	AddTags         []string `gorm:"serializer:json"`
+	SetMerchant     string
+	MarkTransfer    bool `gorm:"not null;default:false"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (r *Rule) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
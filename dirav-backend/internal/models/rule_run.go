@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RuleRun is a background run of a user's rules over their transactions.
+// The partial unique index allows one running run per user.
+type RuleRun struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;uniqueIndex:idx_rule_runs_running,where:status = 'running';not null"`
+	Status      string    `gorm:"not null"`
+	Overwrite   bool      `gorm:"not null;default:false"`
+	Scanned     int       `gorm:"not null;default:0"`
+	Updated     int       `gorm:"not null;default:0"`
+	Error       string
+	StartedAt   time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (r *RuleRun) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
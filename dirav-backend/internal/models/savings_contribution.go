@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SavingsContribution records money put into a savings goal, or taken out
+// of it when Amount is negative. Money moved from AccountID, and into
+// SavingsAccountID when the goal is kept in an account of its own, is
+// booked as a transfer transaction on each account.
+type SavingsContribution struct {
+	ID                   uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID               uuid.UUID  `gorm:"type:uuid;index;not null"`
+	SavingsGoalID        uuid.UUID  `gorm:"type:uuid;index;not null"`
+	AccountID            *uuid.UUID `gorm:"type:uuid"`
+	SavingsAccountID     *uuid.UUID `gorm:"type:uuid"`
+	AccountTransactionID *uuid.UUID `gorm:"type:uuid"`
+	SavingsTransactionID *uuid.UUID `gorm:"type:uuid"`
+	Amount               float64    `gorm:"not null"`
+	Date                 time.Time  `gorm:"not null"`
+	Note                 string
+	CreatedAt            time.Time
+}
+
+func (s *SavingsContribution) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
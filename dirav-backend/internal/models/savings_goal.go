@@ -15,8 +15,12 @@ type SavingsGoal struct {
 	CurrentAmount float64   `gorm:"default:0"`
 	Deadline      *time.Time
 	IsCompleted   bool `gorm:"default:false"`
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
+	// Priority orders goals when money is allocated by priority; lower
+	// numbers are funded first.
+	Priority  int    `gorm:"not null;default:0"`
+	Category  string `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (s *SavingsGoal) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SavingsInvitation invites whoever signs in with Email to join a savings
+// goal. Status is pending until it is accepted, declined or revoked.
+type SavingsInvitation struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	SavingsGoalID uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvitedBy     uuid.UUID `gorm:"type:uuid;not null"`
+	Email         string    `gorm:"index;not null"`
+	Role          string    `gorm:"not null"`
+	Status        string    `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"not null"`
+	RespondedAt   *time.Time
+	CreatedAt     time.Time
+}
+
+func (i *SavingsInvitation) BeforeCreate(tx *gorm.DB) (err error) {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return
+}
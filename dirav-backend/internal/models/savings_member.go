@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SavingsMember gives a user other than the owner access to a savings
+// goal, as a contributor or a viewer.
+type SavingsMember struct {
+	ID            uuid.UUID `gorm:"type:uuid;primaryKey"`
+	SavingsGoalID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_savings_member"`
+	UserID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_savings_member;index"`
+	Role          string    `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (m *SavingsMember) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return
+}
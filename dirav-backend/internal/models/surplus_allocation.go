@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SurplusAllocation records that a month's surplus was put into savings.
+// The unique user and month keep a surplus from being allocated twice.
+type SurplusAllocation struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_surplus_allocation"`
+	Month     time.Time `gorm:"not null;uniqueIndex:idx_surplus_allocation"`
+	Strategy  string    `gorm:"not null"`
+	Amount    float64   `gorm:"not null"`
+	Allocated float64   `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (a *SurplusAllocation) BeforeCreate(tx *gorm.DB) (err error) {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return
+}
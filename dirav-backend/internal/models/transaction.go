@@ -8,16 +8,27 @@ import (
 )
 
 type Transaction struct {
-	ID              uuid.UUID  `gorm:"type:uuid;primaryKey"`
-	UserID          uuid.UUID  `gorm:"type:uuid;index;not null"`
-	AccountID       *uuid.UUID `gorm:"type:uuid"`
-	Title           string     `gorm:"not null"`
-	Amount          float64    `gorm:"not null"`
-	Type            string     `gorm:"not null"`
-	Category        string
-	TransactionDate time.Time `gorm:"not null"`
-	CreatedAt       time.Time
-	UpdatedAt       time.Time
+	ID               uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	UserID           uuid.UUID  `gorm:"type:uuid;index;not null"`
+	AccountID        *uuid.UUID `gorm:"type:uuid"`
+	Title            string     `gorm:"not null"`
+	Amount           float64    `gorm:"not null"`
+	Type             string     `gorm:"not null"`
+	Category         string
+	TransactionDate  time.Time `gorm:"not null"`
+	ValueDate        *time.Time
+	Counterparty     string
+	Notes            string
+	Merchant         string
+	Tags             []string   `gorm:"serializer:json"`
+	Attachments      []string   `gorm:"serializer:json"`
+	IsTransfer       bool       `gorm:"not null;default:false"`
+	ImportBatchID    *uuid.UUID `gorm:"type:uuid;index"`
+	ExternalID       string     `gorm:"index"`
+	Status           string     `gorm:"not null;default:pending"`
+	ReconciliationID *uuid.UUID `gorm:"type:uuid;index"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 func (t *Transaction) BeforeCreate(tx *gorm.DB) (err error) {
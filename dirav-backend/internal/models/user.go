@@ -13,8 +13,19 @@ type User struct {
 	PasswordHash string    `gorm:"not null"`
 	FirstName    string    `gorm:"not null"`
 	LastName     string    `gorm:"not null"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	// MonthlyAllowance is what the user says they have to live on each
+	// month; budget templates scale to it.
+	MonthlyAllowance *float64
+	// Timezone is an IANA zone name. It decides what day it is for the
+	// user, and so where their months and budget periods begin.
+	Timezone string `gorm:"not null;default:UTC"`
+	// RoundUpGoalID is the savings goal expenses are rounded up into; round-
+	// ups are off when nil. They are drawn from RoundUpAccountID if set.
+	RoundUpGoalID    *uuid.UUID `gorm:"type:uuid"`
+	RoundUpAccountID *uuid.UUID `gorm:"type:uuid"`
+	IsAdmin          bool       `gorm:"not null;default:false"`
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
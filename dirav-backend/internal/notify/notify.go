@@ -0,0 +1,74 @@
+// Package notify delivers messages to users. Handlers depend on the
+// Notifier interface only, so tests can swap in a Recorder and production
+// can swap the log for email or push delivery.
+package notify
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Message is one notification for a user.
+type Message struct {
+	UserID uuid.UUID
+	// Email addresses the message when the recipient may not have an
+	// account yet.
+	Email   string
+	Kind    string
+	Subject string
+	Body    string
+	// Ref is the ID of the record the message is about.
+	Ref uuid.UUID
+}
+
+// Notifier delivers messages.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Log writes messages to the standard logger. It is the default when no
+// delivery channel is configured.
+type Log struct{}
+
+func (Log) Notify(_ context.Context, m Message) error {
+	to := m.Email
+	if to == "" {
+		to = m.UserID.String()
+	}
+	log.Printf("notify %s [%s]: %s", to, m.Kind, m.Subject)
+	return nil
+}
+
+// Recorder keeps every message it is given, for tests to inspect. It is
+// safe for concurrent use.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []Message
+	// Err, when set, is returned from Notify; the message is still
+	// recorded.
+	Err error
+}
+
+func (r *Recorder) Notify(_ context.Context, m Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, m)
+	return r.Err
+}
+
+// Messages returns a copy of the messages recorded so far.
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Message(nil), r.messages...)
+}
+
+// Reset forgets the recorded messages.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+}
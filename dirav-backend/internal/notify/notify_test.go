@@ -0,0 +1,30 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRecorder(t *testing.T) {
+	r := &Recorder{}
+	m := Message{UserID: uuid.New(), Kind: "test", Subject: "hello"}
+	if err := r.Notify(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Err = errors.New("down")
+	if err := r.Notify(context.Background(), m); err == nil {
+		t.Error("expected the configured error")
+	}
+	if got := r.Messages(); len(got) != 2 || got[0] != m {
+		t.Errorf("messages = %+v", got)
+	}
+
+	r.Reset()
+	if len(r.Messages()) != 0 {
+		t.Error("Reset kept messages")
+	}
+}
@@ -0,0 +1,65 @@
+// Package planning does zero-based monthly planning: a month's income is
+// assigned to budgets and savings goals until nothing is left over.
+package planning
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// MonthLayout is how plan months are written in URLs and responses.
+const MonthLayout = "2006-01"
+
+var errInvalidMonth = errors.New("invalid month")
+
+// ParseMonth parses a YYYY-MM month into its first day in UTC.
+func ParseMonth(s string) (time.Time, error) {
+	t, err := time.Parse(MonthLayout, s)
+	if err != nil {
+		return time.Time{}, errInvalidMonth
+	}
+	return t, nil
+}
+
+// MonthRange returns the first day of month and the first day of the next
+// one.
+func MonthRange(month time.Time) (time.Time, time.Time) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// Previous returns the month before month.
+func Previous(month time.Time) time.Time {
+	start, _ := MonthRange(month)
+	return start.AddDate(0, -1, 0)
+}
+
+// Summary is where a plan stands: what there is to assign, what is
+// assigned and what is left. LeftToAssign is negative when more is
+// assigned than the income covers.
+type Summary struct {
+	Income       float64 `json:"income"`
+	Assigned     float64 `json:"assigned"`
+	LeftToAssign float64 `json:"left_to_assign"`
+	OverAssigned bool    `json:"over_assigned"`
+}
+
+// Summarize totals the amounts assigned out of income.
+func Summarize(income float64, amounts []float64) Summary {
+	var assigned float64
+	for _, a := range amounts {
+		assigned += a
+	}
+	s := Summary{
+		Income:       round(income),
+		Assigned:     round(assigned),
+		LeftToAssign: round(income - assigned),
+	}
+	s.OverAssigned = s.LeftToAssign < 0
+	return s
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
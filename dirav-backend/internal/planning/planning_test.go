@@ -0,0 +1,47 @@
+package planning
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMonth(t *testing.T) {
+	m, err := ParseMonth("2025-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v", m)
+	}
+	for _, bad := range []string{"", "2025-13", "2025-03-01", "March"} {
+		if _, err := ParseMonth(bad); err == nil {
+			t.Errorf("ParseMonth(%q) accepted", bad)
+		}
+	}
+}
+
+func TestMonthRangeAndPrevious(t *testing.T) {
+	start, end := MonthRange(time.Date(2024, 12, 17, 0, 0, 0, 0, time.UTC))
+	if start.Format("2006-01-02") != "2024-12-01" || end.Format("2006-01-02") != "2025-01-01" {
+		t.Errorf("range = %v..%v", start, end)
+	}
+	if got := Previous(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)).Format(MonthLayout); got != "2024-12" {
+		t.Errorf("previous = %s", got)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize(1200, []float64{450, 300.5, 200})
+	if s.Assigned != 950.5 || s.LeftToAssign != 249.5 || s.OverAssigned {
+		t.Errorf("summary = %+v", s)
+	}
+
+	over := Summarize(500, []float64{300, 250})
+	if over.LeftToAssign != -50 || !over.OverAssigned {
+		t.Errorf("over-assigned summary = %+v", over)
+	}
+
+	if empty := Summarize(800, nil); empty.LeftToAssign != 800 {
+		t.Errorf("empty plan = %+v", empty)
+	}
+}
@@ -0,0 +1,154 @@
+// Package rules evaluates user-defined categorization rules against
+// transactions.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dirav-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// Engine holds a user's active rules in evaluation order with their regular
+// expressions compiled once.
+type Engine struct {
+	rules []compiled
+}
+
+type compiled struct {
+	rule  models.Rule
+	regex *regexp.Regexp
+}
+
+// Validate checks that a rule has at least one condition and one action and
+// that its regular expression compiles.
+func Validate(r models.Rule) error {
+	if r.TitleContains == "" && r.TitleRegex == "" && r.MinAmount == nil && r.MaxAmount == nil &&
+		r.AccountID == nil && r.TransactionType == "" {
+		return errors.New("rule needs at least one condition")
+	}
+	if r.SetCategory == "" && len(r.AddTags) == 0 && r.SetMerchant == "" && !r.MarkTransfer {
+		return errors.New("rule needs at least one action")
+	}
+	if r.MinAmount != nil && r.MaxAmount != nil && *r.MinAmount > *r.MaxAmount {
+		return errors.New("min_amount is greater than max_amount")
+	}
+	if r.TransactionType != "" && r.TransactionType != "income" && r.TransactionType != "expense" {
+		return errors.New("transaction_type must be income or expense")
+	}
+	if r.TitleRegex != "" {
+		if _, err := regexp.Compile("(?i)" + r.TitleRegex); err != nil {
+			return fmt.Errorf("invalid title_regex: %w", err)
+		}
+	}
+	return nil
+}
+
+// New compiles rules into an engine. Inactive rules are dropped; the rest
+// run by ascending priority, then creation time.
+func New(rs []models.Rule) (*Engine, error) {
+	e := &Engine{}
+	for _, r := range rs {
+		if !r.IsActive {
+			continue
+		}
+		c := compiled{rule: r}
+		if r.TitleRegex != "" {
+			re, err := regexp.Compile("(?i)" + r.TitleRegex)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %w", r.ID, err)
+			}
+			c.regex = re
+		}
+		e.rules = append(e.rules, c)
+	}
+	sort.SliceStable(e.rules, func(i, j int) bool {
+		a, b := e.rules[i].rule, e.rules[j].rule
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	return e, nil
+}
+
+// Empty reports whether the engine has no rules to run.
+func (e *Engine) Empty() bool {
+	return e == nil || len(e.rules) == 0
+}
+
+// Apply runs every matching rule against tx and returns the IDs of the rules
+// that matched. The first matching rule decides category and merchant;
+// later rules only add tags or mark the transfer. Unless overwrite is set,
+// a category or merchant the transaction already has is kept.
+func (e *Engine) Apply(tx *models.Transaction, overwrite bool) []uuid.UUID {
+	if e.Empty() {
+		return nil
+	}
+
+	var matched []uuid.UUID
+	setCategory := overwrite || tx.Category == ""
+	setMerchant := overwrite || tx.Merchant == ""
+	for _, c := range e.rules {
+		if !c.matches(*tx) {
+			continue
+		}
+		matched = append(matched, c.rule.ID)
+
+		if c.rule.SetCategory != "" && setCategory {
+			tx.Category = c.rule.SetCategory
+			setCategory = false
+		}
+		if c.rule.SetMerchant != "" && setMerchant {
+			tx.Merchant = c.rule.SetMerchant
+			setMerchant = false
+		}
+		for _, tag := range c.rule.AddTags {
+			tx.Tags = addTag(tx.Tags, tag)
+		}
+		if c.rule.MarkTransfer {
+			tx.IsTransfer = true
+		}
+	}
+	return matched
+}
+
+func (c compiled) matches(tx models.Transaction) bool {
+	r := c.rule
+	if r.TitleContains != "" && !strings.Contains(strings.ToLower(tx.Title), strings.ToLower(r.TitleContains)) {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(tx.Title) {
+		return false
+	}
+	if r.MinAmount != nil && tx.Amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && tx.Amount > *r.MaxAmount {
+		return false
+	}
+	if r.AccountID != nil && (tx.AccountID == nil || *tx.AccountID != *r.AccountID) {
+		return false
+	}
+	if r.TransactionType != "" && tx.Type != r.TransactionType {
+		return false
+	}
+	return true
+}
+
+func addTag(tags []string, tag string) []string {
+	tag = strings.TrimSpace(tag)
+	if tag == "" {
+		return tags
+	}
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
@@ -0,0 +1,82 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func amount(v float64) *float64 { return &v }
+
+func TestApplyPriorityAndActions(t *testing.T) {
+	account := uuid.New()
+	now := time.Now()
+	engine, err := New([]models.Rule{
+		{ID: uuid.New(), IsActive: true, Priority: 2, TitleContains: "coffee", SetCategory: "Eating out", AddTags: []string{"coffee"}},
+		{ID: uuid.New(), IsActive: true, Priority: 1, TitleRegex: `^starbucks\b`, SetCategory: "Coffee", SetMerchant: "Starbucks", CreatedAt: now},
+		{ID: uuid.New(), IsActive: true, Priority: 1, AccountID: &account, MaxAmount: amount(10), AddTags: []string{"small", "Coffee"}, CreatedAt: now.Add(time.Second)},
+		{ID: uuid.New(), IsActive: false, TitleContains: "coffee", SetCategory: "Ignored"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := models.Transaction{Title: "STARBUCKS coffee #12", Amount: 4.5, Type: "expense", AccountID: &account}
+	matched := engine.Apply(&tx, false)
+	if len(matched) != 3 {
+		t.Fatalf("matched %d rules, want 3", len(matched))
+	}
+	if tx.Category != "Coffee" || tx.Merchant != "Starbucks" {
+		t.Errorf("category/merchant = %q/%q, want Coffee/Starbucks", tx.Category, tx.Merchant)
+	}
+	if want := []string{"small", "Coffee"}; !reflect.DeepEqual(tx.Tags, want) {
+		t.Errorf("tags = %v, want %v", tx.Tags, want)
+	}
+}
+
+func TestApplyKeepsExistingUnlessOverwrite(t *testing.T) {
+	engine, err := New([]models.Rule{
+		{ID: uuid.New(), IsActive: true, TransactionType: "expense", MinAmount: amount(100), SetCategory: "Rent", MarkTransfer: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx := models.Transaction{Title: "Landlord", Amount: 900, Type: "expense", Category: "Housing"}
+	engine.Apply(&tx, false)
+	if tx.Category != "Housing" || !tx.IsTransfer {
+		t.Errorf("got category %q transfer %v, want Housing true", tx.Category, tx.IsTransfer)
+	}
+	engine.Apply(&tx, true)
+	if tx.Category != "Rent" {
+		t.Errorf("overwrite: category = %q, want Rent", tx.Category)
+	}
+
+	small := models.Transaction{Title: "Landlord", Amount: 50, Type: "expense"}
+	if engine.Apply(&small, false) != nil {
+		t.Error("rule matched an amount below min_amount")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		rule models.Rule
+		ok   bool
+	}{
+		{"valid", models.Rule{TitleContains: "x", SetCategory: "y"}, true},
+		{"no condition", models.Rule{SetCategory: "y"}, false},
+		{"no action", models.Rule{TitleContains: "x"}, false},
+		{"bad regex", models.Rule{TitleRegex: "(", SetCategory: "y"}, false},
+		{"bad range", models.Rule{MinAmount: amount(5), MaxAmount: amount(1), SetCategory: "y"}, false},
+		{"bad type", models.Rule{TransactionType: "transfer", SetCategory: "y"}, false},
+	}
+	for _, c := range cases {
+		if err := Validate(c.rule); (err == nil) != c.ok {
+			t.Errorf("%s: Validate = %v", c.name, err)
+		}
+	}
+}
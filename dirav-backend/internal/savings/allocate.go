@@ -0,0 +1,158 @@
+package savings
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+// Strategies for splitting money between goals.
+const (
+	// StrategyPriority fills goals one at a time, lowest Priority first.
+	StrategyPriority = "priority"
+	// StrategyProportional splits money by what each goal still needs.
+	StrategyProportional = "proportional"
+	// StrategyDeadline splits money by what each goal needs per day until
+	// its deadline, so goals due soon get more.
+	StrategyDeadline = "deadline"
+)
+
+// ValidStrategy reports whether s is a supported allocation strategy.
+func ValidStrategy(s string) bool {
+	switch s {
+	case StrategyPriority, StrategyProportional, StrategyDeadline:
+		return true
+	}
+	return false
+}
+
+// Allocate splits amount between the goals with strategy as of today. It
+// returns each goal's share, in order, and what is left once every goal is
+// funded. No goal gets more than it lacks of its target, so completed goals
+// get nothing. Shares are whole cents and add up to amount with the rest.
+func Allocate(goals []models.SavingsGoal, amount float64, strategy string, today time.Time) ([]float64, float64) {
+	budget := toCents(amount)
+	need := make([]int64, len(goals))
+	for i, g := range goals {
+		if lacks := toCents(g.TargetAmount - g.CurrentAmount); lacks > 0 {
+			need[i] = lacks
+		}
+	}
+	got := make([]int64, len(goals))
+
+	switch strategy {
+	case StrategyPriority:
+		order := make([]int, len(goals))
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return fundsFirst(goals[order[a]], goals[order[b]])
+		})
+		for _, i := range order {
+			take := min(budget, need[i])
+			got[i] = take
+			budget -= take
+		}
+	case StrategyProportional:
+		weight := make([]float64, len(goals))
+		for i := range goals {
+			weight[i] = float64(need[i])
+		}
+		budget = fill(got, need, weight, budget)
+	case StrategyDeadline:
+		// Goals without a deadline share what is left once every goal
+		// with one is funded.
+		today = dateOf(today)
+		dated := make([]float64, len(goals))
+		undated := make([]float64, len(goals))
+		for i, g := range goals {
+			if g.Deadline == nil {
+				undated[i] = float64(need[i])
+				continue
+			}
+			days := max(1, daysBetween(today, *g.Deadline))
+			dated[i] = float64(need[i]) / float64(days)
+		}
+		budget = fill(got, need, dated, budget)
+		budget = fill(got, need, undated, budget)
+	}
+
+	shares := make([]float64, len(goals))
+	for i, c := range got {
+		shares[i] = float64(c) / 100
+	}
+	return shares, float64(budget) / 100
+}
+
+// fundsFirst orders goals for the priority strategy: by Priority, then
+// earliest deadline, goals without one last, then oldest.
+func fundsFirst(a, b models.SavingsGoal) bool {
+	if a.Priority != b.Priority {
+		return a.Priority < b.Priority
+	}
+	switch {
+	case a.Deadline != nil && b.Deadline != nil && !a.Deadline.Equal(*b.Deadline):
+		return a.Deadline.Before(*b.Deadline)
+	case a.Deadline != nil && b.Deadline == nil:
+		return true
+	case a.Deadline == nil && b.Deadline != nil:
+		return false
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// fill shares budget cents between the goals with a positive weight, in
+// proportion to it. A goal whose share would exceed what it needs is
+// filled and the excess shared among the rest. It returns the cents left
+// once every weighted goal is full.
+func fill(got, need []int64, weight []float64, budget int64) int64 {
+	for budget > 0 {
+		var open []int
+		var total float64
+		for i, w := range weight {
+			if w > 0 && got[i] < need[i] {
+				open = append(open, i)
+				total += w
+			}
+		}
+		if len(open) == 0 {
+			return budget
+		}
+
+		capped := false
+		for _, i := range open {
+			if lacks := need[i] - got[i]; float64(budget)*weight[i]/total >= float64(lacks) {
+				got[i] = need[i]
+				budget -= lacks
+				capped = true
+			}
+		}
+		if capped {
+			continue
+		}
+
+		// Every share is now below what its goal needs. Rounding the
+		// running total keeps the shares whole cents that add up to
+		// budget, each within a cent of its exact share.
+		var sum float64
+		var given int64
+		for k, i := range open {
+			sum += weight[i]
+			upto := budget
+			if k < len(open)-1 {
+				upto = min(budget, int64(math.Floor(float64(budget)*sum/total)))
+			}
+			got[i] += upto - given
+			given = upto
+		}
+		budget = 0
+	}
+	return budget
+}
+
+func toCents(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
@@ -0,0 +1,88 @@
+package savings
+
+import (
+	"reflect"
+	"testing"
+
+	"dirav-backend/internal/models"
+)
+
+func TestAllocatePriority(t *testing.T) {
+	soon := date("2025-06-30")
+	goals := []models.SavingsGoal{
+		{Name: "car", TargetAmount: 300, Priority: 2},
+		{Name: "holiday", TargetAmount: 500, CurrentAmount: 300, Priority: 1},
+		{Name: "gift", TargetAmount: 100, Priority: 1, Deadline: &soon},
+		{Name: "done", TargetAmount: 50, CurrentAmount: 50},
+	}
+
+	// Equal priorities fund the earliest deadline first.
+	shares, left := Allocate(goals, 450, StrategyPriority, date("2025-03-01"))
+	if want := []float64{150, 200, 100, 0}; !reflect.DeepEqual(shares, want) || left != 0 {
+		t.Errorf("got %v, %v left; want %v, 0 left", shares, left, want)
+	}
+
+	shares, left = Allocate(goals, 1000, StrategyPriority, date("2025-03-01"))
+	if want := []float64{300, 200, 100, 0}; !reflect.DeepEqual(shares, want) || left != 400 {
+		t.Errorf("more than needed: got %v, %v left; want %v, 400 left", shares, left, want)
+	}
+}
+
+func TestAllocateProportional(t *testing.T) {
+	goals := []models.SavingsGoal{
+		{TargetAmount: 100},
+		{TargetAmount: 400, CurrentAmount: 100},
+	}
+	shares, left := Allocate(goals, 200, StrategyProportional, date("2025-03-01"))
+	if want := []float64{50, 150}; !reflect.DeepEqual(shares, want) || left != 0 {
+		t.Errorf("got %v, %v left; want %v, 0 left", shares, left, want)
+	}
+
+	// Shares stay in whole cents and add up to the amount.
+	thirds := []models.SavingsGoal{{TargetAmount: 100}, {TargetAmount: 100}, {TargetAmount: 100}}
+	shares, _ = Allocate(thirds, 100, StrategyProportional, date("2025-03-01"))
+	if want := []float64{33.33, 33.33, 33.34}; !reflect.DeepEqual(shares, want) {
+		t.Errorf("thirds: got %v, want %v", shares, want)
+	}
+}
+
+func TestAllocateDeadline(t *testing.T) {
+	today := date("2025-03-01")
+	near, far := date("2025-03-11"), date("2025-05-30")
+	// 100 over 10 days and 900 over 90 days both need 10 a day.
+	goals := []models.SavingsGoal{
+		{TargetAmount: 100, Deadline: &near},
+		{TargetAmount: 900, Deadline: &far},
+		{TargetAmount: 500},
+	}
+
+	tests := []struct {
+		amount float64
+		want   []float64
+		left   float64
+	}{
+		{150, []float64{75, 75, 0}, 0},
+		// The near goal is full at 100; the rest moves to the far one.
+		{300, []float64{100, 200, 0}, 0},
+		// Goals without a deadline get what the dated ones do not need.
+		{1200, []float64{100, 900, 200}, 0},
+		{2000, []float64{100, 900, 500}, 500},
+	}
+	for _, tt := range tests {
+		shares, left := Allocate(goals, tt.amount, StrategyDeadline, today)
+		if !reflect.DeepEqual(shares, tt.want) || left != tt.left {
+			t.Errorf("%v: got %v, %v left; want %v, %v left", tt.amount, shares, left, tt.want, tt.left)
+		}
+	}
+}
+
+func TestValidStrategy(t *testing.T) {
+	for _, s := range []string{StrategyPriority, StrategyProportional, StrategyDeadline} {
+		if !ValidStrategy(s) {
+			t.Errorf("%s should be valid", s)
+		}
+	}
+	if ValidStrategy("random") {
+		t.Error("random should not be valid")
+	}
+}
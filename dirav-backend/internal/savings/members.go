@@ -0,0 +1,32 @@
+package savings
+
+// Roles a user can have on a goal. The user who created a goal owns it;
+// others join it by invitation as contributors or viewers.
+const (
+	RoleOwner       = "owner"
+	RoleContributor = "contributor"
+	RoleViewer      = "viewer"
+)
+
+// ValidMemberRole reports whether r can be given to an invited member.
+// There is only ever one owner.
+func ValidMemberRole(r string) bool {
+	return r == RoleContributor || r == RoleViewer
+}
+
+// CanView reports whether role r may see a goal, its contributions and its
+// members.
+func CanView(r string) bool {
+	return r == RoleOwner || r == RoleContributor || r == RoleViewer
+}
+
+// CanContribute reports whether role r may put money into a goal.
+func CanContribute(r string) bool {
+	return r == RoleOwner || r == RoleContributor
+}
+
+// CanManage reports whether role r may change, withdraw from or delete a
+// goal and decide who else has access.
+func CanManage(r string) bool {
+	return r == RoleOwner
+}
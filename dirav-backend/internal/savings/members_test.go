@@ -0,0 +1,24 @@
+package savings
+
+import "testing"
+
+func TestRoles(t *testing.T) {
+	cases := []struct {
+		role                     string
+		view, contribute, manage bool
+	}{
+		{RoleOwner, true, true, true},
+		{RoleContributor, true, true, false},
+		{RoleViewer, true, false, false},
+		{"", false, false, false},
+	}
+	for _, c := range cases {
+		if CanView(c.role) != c.view || CanContribute(c.role) != c.contribute || CanManage(c.role) != c.manage {
+			t.Errorf("%q: view %v, contribute %v, manage %v; want %v, %v, %v", c.role,
+				CanView(c.role), CanContribute(c.role), CanManage(c.role), c.view, c.contribute, c.manage)
+		}
+	}
+	if ValidMemberRole(RoleOwner) || !ValidMemberRole(RoleViewer) {
+		t.Error("only contributor and viewer can be given to members")
+	}
+}
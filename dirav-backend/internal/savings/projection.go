@@ -0,0 +1,105 @@
+package savings
+
+import (
+	"math"
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+// PaceWindow is how many days back contributions set a goal's pace.
+const PaceWindow = 90
+
+// Average lengths used to turn a daily pace into weekly and monthly ones.
+const (
+	daysPerWeek  = 7
+	daysPerMonth = 365.25 / 12
+)
+
+// Projection statuses.
+const (
+	StatusCompleted  = "completed"
+	StatusOnTrack    = "on_track"
+	StatusBehind     = "behind"
+	StatusNoDeadline = "no_deadline"
+)
+
+// Projection is where a goal is heading: what it takes to finish by the
+// deadline and when it finishes at the pace saved so far.
+type Projection struct {
+	Remaining float64 `json:"remaining"`
+	// DaysLeft counts the days until the deadline, negative once it has
+	// passed; nil without a deadline, as are the required amounts.
+	DaysLeft         *int     `json:"days_left"`
+	RequiredPerWeek  *float64 `json:"required_per_week"`
+	RequiredPerMonth *float64 `json:"required_per_month"`
+	PacePerWeek      float64  `json:"pace_per_week"`
+	PacePerMonth     float64  `json:"pace_per_month"`
+	// ProjectedCompletion is the date the goal is reached at the current
+	// pace; nil when nothing is being saved or the goal is complete.
+	ProjectedCompletion *string `json:"projected_completion"`
+	Status              string  `json:"status"`
+}
+
+// PaceDays is how many days the pace of a goal created on created is
+// measured over as of today: the pace window, or the goal's whole life
+// when it is younger.
+func PaceDays(created, today time.Time) int {
+	days := daysBetween(created, today) + 1
+	if days > PaceWindow {
+		return PaceWindow
+	}
+	if days < 1 {
+		return 1
+	}
+	return days
+}
+
+// Project projects the goal as of today from saved, the net amount
+// contributed over the last paceDays days. A goal is on track when its
+// projected completion falls on or before the deadline.
+func Project(goal models.SavingsGoal, saved float64, paceDays int, today time.Time) Projection {
+	today = dateOf(today)
+	p := Projection{Remaining: math.Max(0, round(goal.TargetAmount-goal.CurrentAmount))}
+
+	var perDay float64
+	if paceDays > 0 && saved > 0 {
+		perDay = saved / float64(paceDays)
+	}
+	p.PacePerWeek = round(perDay * daysPerWeek)
+	p.PacePerMonth = round(perDay * daysPerMonth)
+
+	if goal.Deadline != nil {
+		left := daysBetween(today, *goal.Deadline)
+		weeks := math.Max(1, float64(left)/daysPerWeek)
+		months := math.Max(1, float64(left)/daysPerMonth)
+		perWeek, perMonth := round(p.Remaining/weeks), round(p.Remaining/months)
+		p.DaysLeft, p.RequiredPerWeek, p.RequiredPerMonth = &left, &perWeek, &perMonth
+	}
+
+	if Completed(goal) {
+		p.Status = StatusCompleted
+		return p
+	}
+
+	var projected *time.Time
+	if perDay > 0 {
+		d := today.AddDate(0, 0, int(math.Ceil(p.Remaining/perDay)))
+		s := d.Format("2006-01-02")
+		projected, p.ProjectedCompletion = &d, &s
+	}
+
+	switch {
+	case goal.Deadline == nil:
+		p.Status = StatusNoDeadline
+	case projected != nil && !projected.After(dateOf(*goal.Deadline)):
+		p.Status = StatusOnTrack
+	default:
+		p.Status = StatusBehind
+	}
+	return p
+}
+
+func daysBetween(a, b time.Time) int {
+	return int(math.Round(dateOf(b).Sub(dateOf(a)).Hours() / 24))
+}
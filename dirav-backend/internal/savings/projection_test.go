@@ -0,0 +1,72 @@
+package savings
+
+import (
+	"testing"
+
+	"dirav-backend/internal/models"
+)
+
+func TestPaceDays(t *testing.T) {
+	today := date("2025-06-30")
+	if got := PaceDays(date("2025-06-21"), today); got != 10 {
+		t.Errorf("young goal: %d, want 10", got)
+	}
+	if got := PaceDays(date("2024-01-01"), today); got != PaceWindow {
+		t.Errorf("old goal: %d, want %d", got, PaceWindow)
+	}
+	if got := PaceDays(date("2025-07-02"), today); got != 1 {
+		t.Errorf("goal from the future: %d, want 1", got)
+	}
+}
+
+func TestProject(t *testing.T) {
+	today := date("2025-03-01")
+	deadline := date("2025-05-30")
+	goal := models.SavingsGoal{TargetAmount: 1000, CurrentAmount: 400, Deadline: &deadline}
+
+	// 270 over 90 days is 3 a day: 600 more takes 200 days, past the
+	// deadline 90 days away.
+	p := Project(goal, 270, 90, today)
+	if p.Remaining != 600 || *p.DaysLeft != 90 {
+		t.Fatalf("remaining %v, days left %d; want 600, 90", p.Remaining, *p.DaysLeft)
+	}
+	if *p.RequiredPerWeek != 46.67 || *p.RequiredPerMonth != 202.92 {
+		t.Errorf("required %v/week, %v/month; want 46.67, 202.92", *p.RequiredPerWeek, *p.RequiredPerMonth)
+	}
+	if p.PacePerWeek != 21 || p.PacePerMonth != 91.31 {
+		t.Errorf("pace %v/week, %v/month; want 21, 91.31", p.PacePerWeek, p.PacePerMonth)
+	}
+	if *p.ProjectedCompletion != "2025-09-17" || p.Status != StatusBehind {
+		t.Errorf("projected %s, status %s; want 2025-09-17, behind", *p.ProjectedCompletion, p.Status)
+	}
+
+	// At 9 a day it takes 67 days, well before the deadline.
+	if p := Project(goal, 810, 90, today); *p.ProjectedCompletion != "2025-05-07" || p.Status != StatusOnTrack {
+		t.Errorf("faster pace: projected %s, status %s; want 2025-05-07, on_track", *p.ProjectedCompletion, p.Status)
+	}
+}
+
+func TestProjectWithoutPaceOrDeadline(t *testing.T) {
+	today := date("2025-03-01")
+	goal := models.SavingsGoal{TargetAmount: 500, CurrentAmount: 100}
+
+	p := Project(goal, 0, 30, today)
+	if p.ProjectedCompletion != nil || p.DaysLeft != nil || p.RequiredPerWeek != nil {
+		t.Errorf("got %+v, want no projection and no requirements", p)
+	}
+	if p.Status != StatusNoDeadline {
+		t.Errorf("status %s, want no_deadline", p.Status)
+	}
+
+	deadline := date("2025-02-01")
+	goal.Deadline = &deadline
+	p = Project(goal, 0, 30, today)
+	if p.Status != StatusBehind || *p.DaysLeft != -28 || *p.RequiredPerWeek != 400 {
+		t.Errorf("overdue: status %s, days left %d, required %v/week; want behind, -28, 400", p.Status, *p.DaysLeft, *p.RequiredPerWeek)
+	}
+
+	goal.CurrentAmount = 500
+	if p := Project(goal, 0, 30, today); p.Status != StatusCompleted || p.Remaining != 0 {
+		t.Errorf("completed goal: status %s, remaining %v", p.Status, p.Remaining)
+	}
+}
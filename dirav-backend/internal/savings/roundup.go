@@ -0,0 +1,10 @@
+package savings
+
+import "math"
+
+// RoundUp is the spare change of an expense: what it takes to round amount
+// up to the next whole unit. Whole amounts leave nothing.
+func RoundUp(amount float64) float64 {
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	return float64((100-cents%100)%100) / 100
+}
@@ -0,0 +1,19 @@
+package savings
+
+import "testing"
+
+func TestRoundUp(t *testing.T) {
+	cases := map[float64]float64{
+		3.45:  0.55,
+		4.99:  0.01,
+		0.29:  0.71,
+		12:    0,
+		7.1:   0.9,
+		-2.25: 0.75,
+	}
+	for amount, want := range cases {
+		if got := RoundUp(amount); got != want {
+			t.Errorf("RoundUp(%v) = %v, want %v", amount, got, want)
+		}
+	}
+}
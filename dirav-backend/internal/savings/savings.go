@@ -0,0 +1,53 @@
+// Package savings keeps the balances of savings goals. Every change to a
+// goal's amount goes through Apply, so a goal is completed exactly when it
+// holds its target, whichever way the money moved.
+package savings
+
+import (
+	"errors"
+	"math"
+
+	"dirav-backend/internal/models"
+)
+
+// maxAmount bounds a single contribution or withdrawal.
+const maxAmount = 1e9
+
+var (
+	ErrInvalidAmount = errors.New("amount must be positive with at most two decimals")
+	ErrInsufficient  = errors.New("withdrawal exceeds the amount saved")
+)
+
+// ValidAmount reports whether a is a positive amount of whole cents.
+func ValidAmount(a float64) bool {
+	if !(a > 0) || a > maxAmount {
+		return false
+	}
+	cents := a * 100
+	return math.Abs(cents-math.Round(cents)) < 1e-6
+}
+
+// Apply adds amount to the goal, or withdraws it when negative, and
+// recomputes whether the goal is completed. A withdrawal cannot take the
+// goal below zero.
+func Apply(goal *models.SavingsGoal, amount float64) error {
+	if !ValidAmount(math.Abs(amount)) {
+		return ErrInvalidAmount
+	}
+	next := round(goal.CurrentAmount + amount)
+	if next < 0 {
+		return ErrInsufficient
+	}
+	goal.CurrentAmount = next
+	goal.IsCompleted = Completed(*goal)
+	return nil
+}
+
+// Completed reports whether the goal holds its target.
+func Completed(goal models.SavingsGoal) bool {
+	return goal.CurrentAmount >= goal.TargetAmount
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,54 @@
+package savings
+
+import (
+	"errors"
+	"testing"
+
+	"dirav-backend/internal/models"
+)
+
+func TestValidAmount(t *testing.T) {
+	cases := map[float64]bool{
+		25:        true,
+		0.29:      true,
+		19.99:     true,
+		0:         false,
+		-5:        false,
+		1.005:     false,
+		2e9:       false,
+		maxAmount: true,
+	}
+	for a, want := range cases {
+		if got := ValidAmount(a); got != want {
+			t.Errorf("ValidAmount(%v) = %v, want %v", a, got, want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	goal := models.SavingsGoal{TargetAmount: 100, CurrentAmount: 80.1}
+
+	if err := Apply(&goal, 19.9); err != nil {
+		t.Fatal(err)
+	}
+	if goal.CurrentAmount != 100 || !goal.IsCompleted {
+		t.Fatalf("after contributing: %v completed=%v, want 100 completed", goal.CurrentAmount, goal.IsCompleted)
+	}
+
+	if err := Apply(&goal, -0.01); err != nil {
+		t.Fatal(err)
+	}
+	if goal.CurrentAmount != 99.99 || goal.IsCompleted {
+		t.Fatalf("after withdrawing: %v completed=%v, want 99.99 not completed", goal.CurrentAmount, goal.IsCompleted)
+	}
+
+	if err := Apply(&goal, -100); !errors.Is(err, ErrInsufficient) {
+		t.Errorf("overdrawing: err = %v, want ErrInsufficient", err)
+	}
+	if err := Apply(&goal, 0); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("zero: err = %v, want ErrInvalidAmount", err)
+	}
+	if goal.CurrentAmount != 99.99 {
+		t.Errorf("failed changes moved the balance to %v", goal.CurrentAmount)
+	}
+}
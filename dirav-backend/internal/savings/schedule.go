@@ -0,0 +1,86 @@
+package savings
+
+import (
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+// Cadences a contribution schedule can repeat at.
+const (
+	Weekly   = "weekly"
+	Biweekly = "biweekly"
+	Monthly  = "monthly"
+)
+
+// ValidCadence reports whether c is a supported schedule cadence.
+func ValidCadence(c string) bool {
+	switch c {
+	case Weekly, Biweekly, Monthly:
+		return true
+	}
+	return false
+}
+
+// Run returns the date of run number n of a schedule, counting from 0 at
+// start. Weekly runs keep the start's weekday. Monthly runs keep its day,
+// clamped to the end of shorter months, so a schedule starting on the 31st
+// runs 31 Jan, 28 Feb, 31 Mar.
+func Run(start time.Time, cadence string, n int) time.Time {
+	start = dateOf(start)
+	switch cadence {
+	case Weekly:
+		return start.AddDate(0, 0, 7*n)
+	case Biweekly:
+		return start.AddDate(0, 0, 14*n)
+	default:
+		first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+		day := start.Day()
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// NextRun returns the first run of a schedule on or after day.
+func NextRun(start time.Time, cadence string, day time.Time) time.Time {
+	day = dateOf(day)
+	start = dateOf(start)
+	if !day.After(start) {
+		return start
+	}
+
+	n := 0
+	switch cadence {
+	case Weekly:
+		n = int(day.Sub(start).Hours()/24) / 7
+	case Biweekly:
+		n = int(day.Sub(start).Hours()/24) / 14
+	default:
+		n = (day.Year()-start.Year())*12 + int(day.Month()) - int(start.Month()) - 1
+	}
+	if n < 0 {
+		n = 0
+	}
+	for Run(start, cadence, n).Before(day) {
+		n++
+	}
+	return Run(start, cadence, n)
+}
+
+// Capped is amount, or less when stopping at the target: a schedule that
+// stops once the goal is completed puts in no more than the goal lacks.
+func Capped(goal models.SavingsGoal, amount float64, stopAtTarget bool) float64 {
+	if !stopAtTarget {
+		return amount
+	}
+	if left := round(goal.TargetAmount - goal.CurrentAmount); left < amount {
+		return left
+	}
+	return amount
+}
+
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,71 @@
+package savings
+
+import (
+	"testing"
+	"time"
+
+	"dirav-backend/internal/models"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRun(t *testing.T) {
+	cases := []struct {
+		start, cadence string
+		n              int
+		want           string
+	}{
+		{"2025-03-07", Weekly, 0, "2025-03-07"},
+		{"2025-03-07", Weekly, 3, "2025-03-28"},
+		{"2025-03-07", Biweekly, 2, "2025-04-04"},
+		{"2025-01-31", Monthly, 1, "2025-02-28"},
+		{"2025-01-31", Monthly, 2, "2025-03-31"},
+		{"2025-11-15", Monthly, 3, "2026-02-15"},
+	}
+	for _, c := range cases {
+		if got := Run(date(c.start), c.cadence, c.n); !got.Equal(date(c.want)) {
+			t.Errorf("Run(%s, %s, %d) = %s, want %s", c.start, c.cadence, c.n, got.Format("2006-01-02"), c.want)
+		}
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	cases := []struct {
+		start, cadence, day string
+		want                string
+	}{
+		{"2025-03-07", Weekly, "2025-03-01", "2025-03-07"},
+		{"2025-03-07", Weekly, "2025-03-07", "2025-03-07"},
+		{"2025-03-07", Weekly, "2025-03-08", "2025-03-14"},
+		{"2025-03-07", Biweekly, "2025-03-15", "2025-03-21"},
+		{"2025-01-31", Monthly, "2025-02-28", "2025-02-28"},
+		{"2025-01-31", Monthly, "2025-03-01", "2025-03-31"},
+		{"2025-01-10", Monthly, "2025-06-11", "2025-07-10"},
+	}
+	for _, c := range cases {
+		got := NextRun(date(c.start), c.cadence, date(c.day))
+		if !got.Equal(date(c.want)) {
+			t.Errorf("NextRun(%s, %s, %s) = %s, want %s", c.start, c.cadence, c.day, got.Format("2006-01-02"), c.want)
+		}
+	}
+}
+
+func TestCapped(t *testing.T) {
+	goal := models.SavingsGoal{TargetAmount: 500, CurrentAmount: 490}
+	if got := Capped(goal, 25, true); got != 10 {
+		t.Errorf("stopping at target: %v, want 10", got)
+	}
+	if got := Capped(goal, 25, false); got != 25 {
+		t.Errorf("not stopping: %v, want 25", got)
+	}
+	goal.CurrentAmount = 100
+	if got := Capped(goal, 25, true); got != 25 {
+		t.Errorf("far from target: %v, want 25", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Package search turns user-typed search text into Postgres tsquery syntax.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Config is the text search configuration used for both the indexed
+// tsvector and the queries. "simple" does no stemming and knows no stop
+// words, so titles in any language index the same way.
+const Config = "simple"
+
+// Query converts free text into a to_tsquery expression:
+//
+//	coffee place       coffee:* & place:*      (every word, as a prefix)
+//	"coffee place"     coffee <-> place        (exact phrase)
+//	coffee OR tea      coffee:* | tea:*
+//	coffee -starbucks  coffee:* & !starbucks:*
+//
+// Words are split on anything that is not a letter or digit, the same way
+// the Postgres parser splits them, so the result never contains characters
+// that to_tsquery would reject. It returns "" when q holds nothing to
+// search for.
+func Query(q string) string {
+	var terms []string
+	pendingOr := false
+
+	for _, tok := range tokenize(q) {
+		if !tok.phrase && tok.text == "OR" {
+			pendingOr = len(terms) > 0
+			continue
+		}
+
+		words := lexemes(tok.text)
+		if len(words) == 0 {
+			continue
+		}
+
+		var term string
+		if tok.phrase {
+			term = strings.Join(words, " <-> ")
+		} else {
+			// A word with inner punctuation, like "mcdonald's", is a short
+			// phrase whose last part may still be being typed.
+			words[len(words)-1] += ":*"
+			term = strings.Join(words, " <-> ")
+		}
+		if len(words) > 1 {
+			term = "(" + term + ")"
+		}
+		if tok.negate {
+			term = "!" + term
+		}
+
+		if pendingOr {
+			terms[len(terms)-1] = terms[len(terms)-1] + " | " + term
+			pendingOr = false
+			continue
+		}
+		terms = append(terms, term)
+	}
+
+	for i, t := range terms {
+		if strings.Contains(t, " | ") {
+			terms[i] = "(" + t + ")"
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+type token struct {
+	text   string
+	phrase bool
+	negate bool
+}
+
+// tokenize splits q on whitespace, keeping double-quoted phrases together.
+// An unterminated quote runs to the end of the input.
+func tokenize(q string) []token {
+	var tokens []token
+	r := []rune(q)
+	for i := 0; i < len(r); {
+		if unicode.IsSpace(r[i]) {
+			i++
+			continue
+		}
+
+		negate := false
+		if r[i] == '-' && i+1 < len(r) && !unicode.IsSpace(r[i+1]) {
+			negate = true
+			i++
+		}
+
+		if r[i] == '"' {
+			end := i + 1
+			for end < len(r) && r[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, token{text: string(r[i+1 : end]), phrase: true, negate: negate})
+			i = end + 1
+			continue
+		}
+
+		end := i
+		for end < len(r) && !unicode.IsSpace(r[end]) {
+			end++
+		}
+		tokens = append(tokens, token{text: string(r[i:end]), negate: negate})
+		i = end
+	}
+	return tokens
+}
+
+func lexemes(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
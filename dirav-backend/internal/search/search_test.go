@@ -0,0 +1,29 @@
+package search
+
+import "testing"
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		in, want string
+	}{
+		{"coffee", "coffee:*"},
+		{"Coffee  place", "coffee:* & place:*"},
+		{`"coffee place"`, "(coffee <-> place)"},
+		{`"coffee place" march`, "(coffee <-> place) & march:*"},
+		{"coffee OR tea", "(coffee:* | tea:*)"},
+		{"coffee OR tea OR mate rent", "(coffee:* | tea:* | mate:*) & rent:*"},
+		{"coffee -starbucks", "coffee:* & !starbucks:*"},
+		{`-"gift card"`, "!(gift <-> card)"},
+		{"McDonald's", "(mcdonald <-> s:*)"},
+		{"café 2025", "café:* & 2025:*"},
+		{`"unterminated phrase`, "(unterminated <-> phrase)"},
+		{"OR coffee OR", "coffee:*"},
+		{"a & b | !c ' : *", "a:* & b:* & c:*"},
+		{"  - ... ", ""},
+	}
+	for _, c := range cases {
+		if got := Query(c.in); got != c.want {
+			t.Errorf("Query(%q) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
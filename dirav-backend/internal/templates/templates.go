@@ -0,0 +1,112 @@
+// Package templates handles budget templates: starter sets of budgets
+// written for a monthly allowance and scaled to the user's own. The
+// built-in templates are embedded from builtin.json.
+package templates
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/models"
+)
+
+//go:embed builtin.json
+var builtinJSON []byte
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Builtin returns the embedded templates, active and validated.
+func Builtin() ([]models.BudgetTemplate, error) {
+	var list []models.BudgetTemplate
+	if err := json.Unmarshal(builtinJSON, &list); err != nil {
+		return nil, fmt.Errorf("builtin templates: %w", err)
+	}
+	for i := range list {
+		Normalize(&list[i])
+		list[i].IsActive = true
+		if err := Validate(list[i]); err != nil {
+			return nil, fmt.Errorf("builtin template %q: %w", list[i].Slug, err)
+		}
+	}
+	return list, nil
+}
+
+// Normalize trims names and fills in the default period and rollover.
+func Normalize(t *models.BudgetTemplate) {
+	t.Slug = strings.TrimSpace(t.Slug)
+	t.Name = strings.TrimSpace(t.Name)
+	for i := range t.Budgets {
+		it := &t.Budgets[i]
+		it.Name = strings.TrimSpace(it.Name)
+		it.Category = strings.TrimSpace(it.Category)
+		if it.Period == "" {
+			it.Period = budgeting.Monthly
+		}
+		if it.Rollover == "" {
+			it.Rollover = budgeting.RolloverNone
+		}
+	}
+}
+
+// Validate checks that a template can be applied.
+func Validate(t models.BudgetTemplate) error {
+	switch {
+	case !slugPattern.MatchString(t.Slug):
+		return errors.New("slug must be lowercase letters, digits and dashes")
+	case t.Name == "":
+		return errors.New("name is required")
+	case t.Allowance <= 0:
+		return errors.New("allowance must be positive")
+	case len(t.Budgets) == 0:
+		return errors.New("a template needs at least one budget")
+	}
+	for i, it := range t.Budgets {
+		switch {
+		case it.Name == "":
+			return fmt.Errorf("budget %d: name is required", i+1)
+		case it.Amount <= 0:
+			return fmt.Errorf("budget %d: amount must be positive", i+1)
+		case !budgeting.ValidPeriod(it.Period):
+			return fmt.Errorf("budget %d: invalid period", i+1)
+		case !budgeting.ValidRollover(it.Rollover):
+			return fmt.Errorf("budget %d: invalid rollover", i+1)
+		}
+	}
+	return nil
+}
+
+// Scale returns the template's budgets with amounts scaled from the
+// template's allowance to allowance, rounded to cents. When the monthly
+// budgets make up the whole template allowance, rounding is settled on the
+// largest of them so they still add up to exactly the new allowance.
+func Scale(t models.BudgetTemplate, allowance float64) []models.BudgetTemplateItem {
+	factor := allowance / t.Allowance
+	items := make([]models.BudgetTemplateItem, len(t.Budgets))
+	var monthly, scaledMonthly float64
+	largest := -1
+	for i, it := range t.Budgets {
+		items[i] = it
+		items[i].Amount = round(it.Amount * factor)
+		if it.Period == budgeting.Monthly {
+			monthly += it.Amount
+			scaledMonthly += items[i].Amount
+			if largest < 0 || it.Amount > t.Budgets[largest].Amount {
+				largest = i
+			}
+		}
+	}
+	if largest >= 0 && round(monthly) == round(t.Allowance) {
+		items[largest].Amount = round(items[largest].Amount + allowance - scaledMonthly)
+	}
+	return items
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,82 @@
+package templates
+
+import (
+	"testing"
+
+	"dirav-backend/internal/budgeting"
+	"dirav-backend/internal/models"
+)
+
+func TestBuiltin(t *testing.T) {
+	list, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	slugs := map[string]bool{}
+	for _, tpl := range list {
+		slugs[tpl.Slug] = true
+	}
+	for _, want := range []string{"on-campus-student", "commuter", "study-abroad"} {
+		if !slugs[want] {
+			t.Errorf("missing builtin template %q", want)
+		}
+	}
+}
+
+func TestScale(t *testing.T) {
+	tpl := models.BudgetTemplate{
+		Allowance: 300,
+		Budgets: []models.BudgetTemplateItem{
+			{Name: "Food", Amount: 100, Period: budgeting.Monthly},
+			{Name: "Fun", Amount: 100, Period: budgeting.Monthly},
+			{Name: "Rent", Amount: 100, Period: budgeting.Monthly},
+			{Name: "Coffee", Amount: 10, Period: budgeting.Weekly},
+		},
+	}
+	items := Scale(tpl, 1000)
+
+	var monthly float64
+	for _, it := range items {
+		if it.Period == budgeting.Monthly {
+			monthly += it.Amount
+		}
+	}
+	if round(monthly) != 1000 {
+		t.Errorf("monthly budgets add up to %v, want 1000", monthly)
+	}
+	if items[3].Amount != 33.33 {
+		t.Errorf("weekly amount = %v, want 33.33", items[3].Amount)
+	}
+	if tpl.Budgets[0].Amount != 100 {
+		t.Error("Scale changed the template")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good := models.BudgetTemplate{Slug: "night-owl", Name: "Night owl", Allowance: 500, Budgets: []models.BudgetTemplateItem{{Name: "Snacks", Amount: 50}}}
+	Normalize(&good)
+	if err := Validate(good); err != nil {
+		t.Fatalf("valid template rejected: %v", err)
+	}
+
+	bad := []func(*models.BudgetTemplate){
+		func(t *models.BudgetTemplate) { t.Slug = "Night Owl" },
+		func(t *models.BudgetTemplate) { t.Name = "" },
+		func(t *models.BudgetTemplate) { t.Allowance = 0 },
+		func(t *models.BudgetTemplate) { t.Budgets = nil },
+		func(t *models.BudgetTemplate) {
+			t.Budgets = []models.BudgetTemplateItem{{Name: "Snacks", Amount: 0, Period: "monthly", Rollover: "none"}}
+		},
+		func(t *models.BudgetTemplate) {
+			t.Budgets = []models.BudgetTemplateItem{{Name: "Snacks", Amount: 5, Period: "fortnightly", Rollover: "none"}}
+		},
+	}
+	for i, mutate := range bad {
+		tpl := good
+		tpl.Budgets = append([]models.BudgetTemplateItem(nil), good.Budgets...)
+		mutate(&tpl)
+		if err := Validate(tpl); err == nil {
+			t.Errorf("case %d: invalid template accepted", i)
+		}
+	}
+}